@@ -1,36 +1,232 @@
 package collector
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"github.com/prometheus/common/log"
+	"golang.org/x/sync/singleflight"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"sync"
+	"time"
 )
 
+// Target describes a Logstash API and how to reach it
+type Target struct {
+	Endpoint  string
+	Client    *http.Client
+	BasicAuth *BasicAuth
+	// Headers are sent with every request, e.g. for API key authentication.
+	Headers map[string]*Secret
+	// CacheTTL is how long API responses are reused, caching is disabled if 0.
+	CacheTTL time.Duration
+	// Poller, if set, polls node stats in the background and scrapes are
+	// served from its last snapshot.
+	Poller *Poller
+	// PluginMetricKeys, if set, selects the plugin-specific metrics exported.
+	PluginMetricKeys *KeyFilter
+	// HotThreads caps the threads reported by the hot threads collector,
+	// DefaultHotThreads if 0.
+	HotThreads int
+
+	group        singleflight.Group
+	mtx          sync.Mutex
+	flights      map[string]*flight
+	cache        map[string]cacheEntry
+	certNotAfter time.Time
+
+	uptime  int64
+	info    *NodeInfoResponse
+	version string
+}
+
+// BasicAuth holds the credentials used towards the Logstash API
+type BasicAuth struct {
+	Username string
+	Password *Secret
+}
+
+// NewTarget returns a Target for endpoint using the default HTTP client
+func NewTarget(endpoint string) *Target {
+	return &Target{
+		Endpoint: endpoint,
+		Client:   http.DefaultClient,
+	}
+}
+
+// NewTLSClient returns an HTTP client with the same defaults as
+// http.DefaultClient, using tlsConfig towards the Logstash API
+func NewTLSClient(tlsConfig *tls.Config) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+			TLSClientConfig:       tlsConfig,
+		},
+	}
+}
+
+// Adopt takes over the cached responses, node info and node stats snapshot of
+// previous, a target for the same Logstash node that t replaces. The poller
+// of previous must be stopped.
+func (t *Target) Adopt(previous *Target) {
+	previous.mtx.Lock()
+	cache := make(map[string]cacheEntry, len(previous.cache))
+	for path, entry := range previous.cache {
+		cache[path] = entry
+	}
+	certNotAfter, uptime, info, version := previous.certNotAfter, previous.uptime, previous.info, previous.version
+	previous.mtx.Unlock()
+
+	t.mtx.Lock()
+	t.cache, t.certNotAfter, t.uptime, t.info, t.version = cache, certNotAfter, uptime, info, version
+	t.mtx.Unlock()
+
+	if t.Poller != nil && previous.Poller != nil {
+		t.Poller.adopt(previous.Poller)
+	}
+}
+
+// CertificateExpiry returns when the earliest expiring certificate last
+// presented by the Logstash API expires, or the zero time if it has not
+// presented any
+func (t *Target) CertificateExpiry() time.Time {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.certNotAfter
+}
+
+func (t *Target) observeTLS(state *tls.ConnectionState) {
+	var notAfter time.Time
+	for _, cert := range state.PeerCertificates {
+		if notAfter.IsZero() || cert.NotAfter.Before(notAfter) {
+			notAfter = cert.NotAfter
+		}
+	}
+
+	t.mtx.Lock()
+	t.certNotAfter = notAfter
+	t.mtx.Unlock()
+}
+
+func (t *Target) handler(api, path string) HTTPHandlerInterface {
+	return &fetchHandler{
+		target: t,
+		api:    api,
+		path:   path,
+		handler: &HTTPHandler{
+			Endpoint:   t.Endpoint + path,
+			Client:     t.Client,
+			BasicAuth:  t.BasicAuth,
+			Headers:    t.Headers,
+			ObserveTLS: t.observeTLS,
+		},
+	}
+}
+
 // HTTPHandler type
 type HTTPHandler struct {
-	Endpoint string
+	Endpoint  string
+	Client    *http.Client
+	BasicAuth *BasicAuth
+	Headers   map[string]*Secret
+	// ObserveTLS, if set, is called with the TLS state of HTTPS responses.
+	ObserveTLS func(*tls.ConnectionState)
 }
 
 // Get method for HTTPHandler
-func (h *HTTPHandler) Get() (http.Response, error) {
-	response, err := http.Get(h.Endpoint)
+func (h *HTTPHandler) Get(ctx context.Context) (http.Response, error) {
+	request, err := http.NewRequest("GET", h.Endpoint, nil)
 	if err != nil {
 		return http.Response{}, err
 	}
+	request = request.WithContext(ctx)
+
+	if h.BasicAuth != nil {
+		password := ""
+		if h.BasicAuth.Password != nil {
+			if password, err = h.BasicAuth.Password.Get(); err != nil {
+				return http.Response{}, fmt.Errorf("basic auth password: %v", err)
+			}
+		}
+		request.SetBasicAuth(h.BasicAuth.Username, password)
+	}
+
+	for name, secret := range h.Headers {
+		value, err := secret.Get()
+		if err != nil {
+			return http.Response{}, fmt.Errorf("header %s: %v", name, err)
+		}
+		request.Header.Set(name, value)
+	}
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return http.Response{}, err
+	}
+
+	if response.TLS != nil && h.ObserveTLS != nil {
+		h.ObserveTLS(response.TLS)
+	}
 
 	return *response, nil
 }
 
 // HTTPHandlerInterface interface
 type HTTPHandlerInterface interface {
-	Get() (http.Response, error)
+	Get(ctx context.Context) (http.Response, error)
 }
 
-func getMetrics(h HTTPHandlerInterface, target interface{}) error {
-	response, err := h.Get()
+// StatusError is returned when the Logstash API responds with a non-2xx
+// status code, e.g. for APIs older Logstash versions do not have
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code from Logstash: %d", e.StatusCode)
+}
+
+// IsNotFound reports whether err is a 404 response of the Logstash API
+func IsNotFound(err error) bool {
+	statusErr, ok := err.(*StatusError)
+	return ok && statusErr.StatusCode == http.StatusNotFound
+}
+
+func getMetrics(ctx context.Context, h HTTPHandlerInterface, target interface{}) error {
+	body, err := readBody(ctx, h)
+	if _, ok := err.(*StatusError); ok {
+		return err
+	} else if err != nil {
+		return fmt.Errorf("cannot retrieve metrics: %s", err)
+	}
+
+	if err := json.Unmarshal(body, target); err != nil {
+		return fmt.Errorf("cannot parse Logstash response json: %s", err)
+	}
+
+	return nil
+}
+
+func readBody(ctx context.Context, h HTTPHandlerInterface) ([]byte, error) {
+	response, err := h.Get(ctx)
 	if err != nil {
-		log.Errorf("Cannot retrieve metrics: %s", err)
-		return nil
+		return nil, err
 	}
 
 	defer func() {
@@ -40,9 +236,9 @@ func getMetrics(h HTTPHandlerInterface, target interface{}) error {
 		}
 	}()
 
-	if err := json.NewDecoder(response.Body).Decode(target); err != nil {
-		log.Errorf("Cannot parse Logstash response json: %s", err)
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, &StatusError{StatusCode: response.StatusCode}
 	}
 
-	return nil
+	return ioutil.ReadAll(response.Body)
 }
@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestHTTPHandlerCredentials(t *testing.T) {
+	file, err := ioutil.TempFile("", "logstash_exporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	writeSecret(t, file.Name(), "first\n", time.Now().Add(-time.Minute))
+
+	var username, password, apiKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ = r.BasicAuth()
+		apiKey = r.Header.Get("X-Api-Key")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	target := NewTarget(server.URL)
+	target.BasicAuth = &BasicAuth{Username: "exporter", Password: NewSecret("", file.Name())}
+	target.Headers = map[string]*Secret{"X-Api-Key": NewSecret("abc", "")}
+
+	var response NodeInfoResponse
+	if err := getMetrics(context.Background(), target.handler("node_info", "/_node"), &response); err != nil {
+		t.Fatal(err)
+	}
+	if username != "exporter" || password != "first" {
+		t.Errorf("expected basic auth exporter:first, got %s:%s", username, password)
+	}
+	if apiKey != "abc" {
+		t.Errorf("expected X-Api-Key header, got %q", apiKey)
+	}
+
+	writeSecret(t, file.Name(), "second", time.Now())
+	if err := getMetrics(context.Background(), target.handler("node_info", "/_node"), &response); err != nil {
+		t.Fatal(err)
+	}
+	if password != "second" {
+		t.Errorf("expected the rotated password, got %s", password)
+	}
+}
+
+func writeSecret(t *testing.T, filename, content string, modTime time.Time) {
+	if err := ioutil.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filename, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTPHandlerTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+
+	target := NewTarget(server.URL)
+	target.Client = NewTLSClient(&tls.Config{RootCAs: pool})
+
+	var response NodeInfoResponse
+	if err := getMetrics(context.Background(), target.handler("node_info", "/_node"), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	if expiry := target.CertificateExpiry(); !expiry.Equal(server.Certificate().NotAfter) {
+		t.Errorf("expected certificate expiry %s, got %s", server.Certificate().NotAfter, expiry)
+	}
+
+	untrusted := NewTarget(server.URL)
+	if err := getMetrics(context.Background(), untrusted.handler("node_info", "/_node"), &response); err == nil {
+		t.Error("expected an error for a certificate not chaining to the system pool")
+	}
+}
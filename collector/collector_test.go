@@ -0,0 +1,136 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var fqNameRegexp = regexp.MustCompile(`fqName: "([^"]+)"`)
+
+// collectValues runs the collector built by newCollector against a Logstash
+// API serving responses by path, and returns the collected values keyed by
+// metric name and sorted labels, e.g. logstash_up{collector="node"}
+func collectValues(t *testing.T, newCollector func(*Target) (Collector, error), responses map[string][]byte) map[string]float64 {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(response)
+	}))
+	defer server.Close()
+
+	c, err := newCollector(NewTarget(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch := make(chan prometheus.Metric)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.Collect(context.Background(), ch)
+		close(ch)
+	}()
+
+	values := make(map[string]float64)
+	for metric := range ch {
+		var m dto.Metric
+		if err := metric.Write(&m); err != nil {
+			t.Fatal(err)
+		}
+
+		labels := make([]string, 0, len(m.Label))
+		for _, lp := range m.Label {
+			labels = append(labels, fmt.Sprintf("%s=%q", lp.GetName(), lp.GetValue()))
+		}
+		sort.Strings(labels)
+
+		key := fqNameRegexp.FindStringSubmatch(metric.Desc().String())[1]
+		if len(labels) > 0 {
+			key += "{" + strings.Join(labels, ",") + "}"
+		}
+
+		switch {
+		case m.Gauge != nil:
+			values[key] = m.Gauge.GetValue()
+		case m.Counter != nil:
+			values[key] = m.Counter.GetValue()
+		default:
+			values[key] = m.Untyped.GetValue()
+		}
+	}
+
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+// collectorTest is a case of a table-driven collector test. Every metric
+// family in expected must be collected with exactly the series given, and no
+// metric whose name starts with one of absent may be collected.
+type collectorTest struct {
+	name      string
+	responses map[string][]byte
+	expected  map[string]float64
+	absent    []string
+}
+
+func runCollectorTests(t *testing.T, newCollector func(*Target) (Collector, error), tests []collectorTest) {
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values := collectValues(t, newCollector, test.responses)
+			expectSeries(t, values, test.expected)
+
+			for key := range values {
+				for _, prefix := range test.absent {
+					if strings.HasPrefix(key, prefix) {
+						t.Errorf("expected no %s metrics, got %s", prefix, key)
+					}
+				}
+			}
+		})
+	}
+}
+
+// expectSeries checks expected like expectValues, and that no other series
+// of the metric families in expected are collected
+func expectSeries(t *testing.T, values map[string]float64, expected map[string]float64) {
+	expectValues(t, values, expected)
+
+	families := make(map[string]bool)
+	for key := range expected {
+		families[metricFamily(key)] = true
+	}
+	for key := range values {
+		if _, ok := expected[key]; !ok && families[metricFamily(key)] {
+			t.Errorf("expected %s not to be collected", key)
+		}
+	}
+}
+
+func metricFamily(key string) string {
+	return strings.SplitN(key, "{", 2)[0]
+}
+
+func expectValues(t *testing.T, values map[string]float64, expected map[string]float64) {
+	for key, value := range expected {
+		actual, ok := values[key]
+		if !ok {
+			t.Errorf("expected %s to be collected", key)
+			continue
+		}
+		if actual != value {
+			t.Errorf("expected %s to be %v, got %v", key, value, actual)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+var (
+	// CacheHits counts Logstash API responses served from the cache or
+	// shared with a concurrent request for the same API
+	CacheHits = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "exporter",
+			Name:      "api_cache_hits_total",
+			Help:      "logstash_exporter: Logstash API responses served from the cache or shared with a concurrent request.",
+		},
+		[]string{"api"},
+	)
+
+	// CacheMisses counts requests actually sent to the Logstash API
+	CacheMisses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "exporter",
+			Name:      "api_cache_misses_total",
+			Help:      "logstash_exporter: Requests sent to the Logstash API.",
+		},
+		[]string{"api"},
+	)
+)
+
+type cacheEntry struct {
+	body    []byte
+	expires time.Time
+}
+
+// fetchHandler gets a Logstash API through its Target, so that concurrent
+// requests for the same API share one round trip and responses are cached
+// for the target's CacheTTL. The cache metrics are labeled by api rather
+// than path, which can contain arbitrary pipeline IDs.
+type fetchHandler struct {
+	target  *Target
+	api     string
+	path    string
+	handler HTTPHandlerInterface
+}
+
+// Get method for fetchHandler
+func (h *fetchHandler) Get(ctx context.Context) (http.Response, error) {
+	body, err := h.target.fetch(ctx, h.api, h.path, h.handler)
+	if err != nil {
+		return http.Response{}, err
+	}
+
+	return http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+	}, nil
+}
+
+// fetch returns the body of the API at path, from the cache if it is fresh
+// or from the request in flight for it if there is one. The request in flight
+// is not bound to the context of any one caller, it is canceled once every
+// caller has given up on it, so it lasts until the longest deadline among them.
+func (t *Target) fetch(ctx context.Context, api, path string, h HTTPHandlerInterface) ([]byte, error) {
+	if body, ok := t.cached(path); ok {
+		CacheHits.WithLabelValues(api).Inc()
+		return body, nil
+	}
+
+	f := t.join(path)
+	defer t.leave(path, f)
+
+	sent := false
+	ch := t.group.DoChan(path, func() (interface{}, error) {
+		sent = true
+		CacheMisses.WithLabelValues(api).Inc()
+		body, err := readBody(f.ctx, h)
+		if err == nil && t.CacheTTL > 0 {
+			t.store(path, body)
+		}
+		return body, err
+	})
+
+	select {
+	case res := <-ch:
+		if !sent {
+			CacheHits.WithLabelValues(api).Inc()
+		}
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.([]byte), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// flight is the context shared by the callers waiting for a request to path
+type flight struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	callers int
+}
+
+func (t *Target) join(path string) *flight {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if t.flights == nil {
+		t.flights = make(map[string]*flight)
+	}
+	f, ok := t.flights[path]
+	if !ok {
+		f = &flight{}
+		f.ctx, f.cancel = context.WithCancel(context.Background())
+		t.flights[path] = f
+	}
+	f.callers++
+	return f
+}
+
+// leave cancels the request in flight for path once its last caller is gone,
+// and forgets it so that later callers do not join a canceled request
+func (t *Target) leave(path string, f *flight) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	f.callers--
+	if f.callers > 0 {
+		return
+	}
+	f.cancel()
+	t.group.Forget(path)
+	delete(t.flights, path)
+}
+
+func (t *Target) cached(path string) ([]byte, bool) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	entry, ok := t.cache[path]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.body, true
+}
+
+// store caches body for path and evicts expired entries, so that paths
+// requested once, such as graphs of unknown pipelines, are not kept
+func (t *Target) store(path string, body []byte) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	now := time.Now()
+	if t.cache == nil {
+		t.cache = make(map[string]cacheEntry)
+	}
+	for p, entry := range t.cache {
+		if now.After(entry.expires) {
+			delete(t.cache, p)
+		}
+	}
+	t.cache[path] = cacheEntry{body: body, expires: now.Add(t.CacheTTL)}
+}
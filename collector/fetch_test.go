@@ -0,0 +1,159 @@
+package collector
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTargetCache(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"version": "6.2.4"}`))
+	}))
+	defer server.Close()
+
+	target := NewTarget(server.URL)
+	target.CacheTTL = time.Minute
+
+	for i := 0; i < 3; i++ {
+		response, err := NodeInfo(context.Background(), target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Version != "6.2.4" {
+			t.Errorf("expected version 6.2.4, got %q", response.Version)
+		}
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("expected 1 request to Logstash, got %d", n)
+	}
+
+	target.CacheTTL = 0
+	target.cache = nil
+	for i := 0; i < 2; i++ {
+		if _, err := NodeInfo(context.Background(), target); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Errorf("expected uncached requests to reach Logstash, got %d requests", n)
+	}
+}
+
+func TestTargetCacheSkipsErrors(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			http.Error(w, "starting up", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	target := NewTarget(server.URL)
+	target.CacheTTL = time.Minute
+
+	if _, err := NodeInfo(context.Background(), target); err == nil {
+		t.Error("expected an error for a non-2xx status code")
+	}
+	if _, err := NodeInfo(context.Background(), target); err != nil {
+		t.Errorf("expected errors not to be cached, got %v", err)
+	}
+}
+
+func TestTargetCacheEvictsExpiredEntries(t *testing.T) {
+	target := NewTarget("http://localhost:9600")
+	target.CacheTTL = time.Minute
+
+	target.store("/_node/pipelines/unknown?graph=true", []byte(`{}`))
+	target.cache["/_node/pipelines/unknown?graph=true"] = cacheEntry{expires: time.Now().Add(-time.Second)}
+	target.store("/_node", []byte(`{}`))
+
+	if _, ok := target.cache["/_node/pipelines/unknown?graph=true"]; ok {
+		t.Error("expected the expired entry to be evicted")
+	}
+	if len(target.cache) != 1 {
+		t.Errorf("expected 1 cache entry, got %d", len(target.cache))
+	}
+}
+
+func TestTargetCacheMetricsByAPI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"pipelines": {}}`))
+	}))
+	defer server.Close()
+
+	target := NewTarget(server.URL)
+	before := counterValue(t, CacheMisses.WithLabelValues("pipeline_graph"))
+	for _, id := range []string{"a", "b", "c"} {
+		NodePipelineGraph(context.Background(), target, id)
+	}
+
+	if n := counterValue(t, CacheMisses.WithLabelValues("pipeline_graph")) - before; n != 3 {
+		t.Errorf("expected 3 misses for pipeline_graph, got %v", n)
+	}
+}
+
+func TestTargetSharedRequestOutlivesFirstCaller(t *testing.T) {
+	var requests int32
+	received, release := make(chan struct{}), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		close(received)
+		<-release
+		w.Write([]byte(`{"version": "6.2.4"}`))
+	}))
+	defer server.Close()
+
+	target := NewTarget(server.URL)
+	handler := target.handler("node_info", "/_node")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := handler.Get(ctx)
+		first <- err
+	}()
+	<-received
+
+	second := make(chan error)
+	go func() {
+		_, err := handler.Get(context.Background())
+		second <- err
+	}()
+	for joined := false; !joined; time.Sleep(time.Millisecond) {
+		target.mtx.Lock()
+		joined = target.flights["/_node"].callers == 2
+		target.mtx.Unlock()
+	}
+
+	cancel()
+	if err := <-first; err != context.Canceled {
+		t.Errorf("expected the first caller to give up, got %v", err)
+	}
+	close(release)
+	if err := <-second; err != nil {
+		t.Errorf("expected the joined caller to get the response, got %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("expected 1 request to Logstash, got %d", n)
+	}
+}
+
+func counterValue(t *testing.T, counter prometheus.Counter) float64 {
+	var m dto.Metric
+	if err := counter.Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetCounter().GetValue()
+}
@@ -0,0 +1,38 @@
+package collector
+
+import "context"
+
+// HealthReportResponse type
+type HealthReportResponse struct {
+	Host       string                     `json:"host"`
+	Version    string                     `json:"version"`
+	Status     string                     `json:"status"`
+	Indicators map[string]HealthIndicator `json:"indicators"`
+}
+
+// HealthIndicator reports the health of a part of Logstash, e.g. all
+// pipelines, and may break it down into nested indicators, e.g. per pipeline
+type HealthIndicator struct {
+	Status     string                     `json:"status"`
+	Diagnosis  []HealthDiagnosis          `json:"diagnosis"`
+	Indicators map[string]HealthIndicator `json:"indicators"`
+}
+
+// HealthDiagnosis describes a cause of an unhealthy indicator
+type HealthDiagnosis struct {
+	ID      string `json:"id"`
+	Cause   string `json:"cause"`
+	Action  string `json:"action"`
+	HelpURL string `json:"help_url"`
+}
+
+// HealthReport function, only supported by Logstash >=8.16
+func HealthReport(ctx context.Context, target *Target) (HealthReportResponse, error) {
+	var response HealthReportResponse
+
+	handler := target.handler("health_report", "/_health_report")
+
+	err := getMetrics(ctx, handler, &response)
+
+	return response, err
+}
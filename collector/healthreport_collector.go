@@ -0,0 +1,124 @@
+package collector
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"strings"
+)
+
+// healthStatuses are the statuses of the health report, one series of each
+// health status metric is exported per status
+var healthStatuses = []string{"green", "yellow", "red", "unknown"}
+
+// HealthReportCollector type
+type HealthReportCollector struct {
+	target *Target
+
+	Status          *prometheus.Desc
+	IndicatorStatus *prometheus.Desc
+	DiagnosisInfos  *prometheus.Desc
+}
+
+// NewHealthReportCollector function
+func NewHealthReportCollector(target *Target) (Collector, error) {
+	const subsystem = "health"
+
+	return &HealthReportCollector{
+		target: target,
+
+		Status: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "status"),
+			"Overall health of Logstash, 1 for the current status and 0 for the others.",
+			[]string{"status"},
+			nil,
+		),
+
+		IndicatorStatus: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "indicator_status"),
+			"Health of an indicator, such as pipelines/main, 1 for the current status and 0 for the others.",
+			[]string{"indicator", "status"},
+			nil,
+		),
+
+		DiagnosisInfos: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "indicator_diagnosis_info"),
+			"A metric with a constant '1' value labeled by the ID of a diagnosis of an unhealthy indicator.",
+			[]string{"indicator", "diagnosis_id"},
+			nil,
+		),
+	}, nil
+}
+
+// Collect function implements healthreport_collector collector
+func (c *HealthReportCollector) Collect(ctx context.Context, ch chan<- prometheus.Metric) error {
+	if desc, err := c.collect(ctx, ch); err != nil {
+		log.Error("Failed collecting health report metrics", desc, err)
+		return err
+	}
+	return nil
+}
+
+func (c *HealthReportCollector) collect(ctx context.Context, ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
+	report, err := HealthReport(ctx, c.target)
+	if IsNotFound(err) {
+		log.Debugf("Health report not supported by Logstash at %s", c.target.Endpoint)
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	c.collectStatus(ch, c.Status, report.Status)
+	c.collectIndicators(ch, nil, report.Indicators)
+
+	return nil, nil
+}
+
+func (c *HealthReportCollector) collectIndicators(ch chan<- prometheus.Metric, parents []string, indicators map[string]HealthIndicator) {
+	for name, indicator := range indicators {
+		path := append(append([]string{}, parents...), name)
+		indicatorName := strings.Join(path, "/")
+
+		c.collectStatus(ch, c.IndicatorStatus, indicator.Status, indicatorName)
+
+		seen := make(map[string]bool, len(indicator.Diagnosis))
+		for _, diagnosis := range indicator.Diagnosis {
+			if seen[diagnosis.ID] {
+				continue
+			}
+			seen[diagnosis.ID] = true
+
+			ch <- prometheus.MustNewConstMetric(
+				c.DiagnosisInfos,
+				prometheus.GaugeValue,
+				float64(1),
+				indicatorName,
+				diagnosis.ID,
+			)
+		}
+
+		c.collectIndicators(ch, path, indicator.Indicators)
+	}
+}
+
+// collectStatus sends one series per known status, set to 1 for status.
+// Statuses Logstash does not report or does not document count as unknown.
+func (c *HealthReportCollector) collectStatus(ch chan<- prometheus.Metric, desc *prometheus.Desc, status string, labels ...string) {
+	status = strings.ToLower(status)
+
+	known := false
+	for _, s := range healthStatuses {
+		known = known || s == status
+	}
+	if !known {
+		status = "unknown"
+	}
+
+	for _, s := range healthStatuses {
+		value := 0.0
+		if s == status {
+			value = 1
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, append(labels, s)...)
+	}
+}
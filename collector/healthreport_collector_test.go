@@ -0,0 +1,69 @@
+package collector
+
+import "testing"
+
+var healthReportJSON = []byte(`
+{
+  "host": "logstash-1",
+  "version": "8.16.0",
+  "status": "yellow",
+  "symptom": "1 indicator is concerning (pipelines)",
+  "indicators": {
+    "pipelines": {
+      "status": "yellow",
+      "symptom": "1 indicator is concerning (main)",
+      "indicators": {
+        "main": {
+          "status": "yellow",
+          "symptom": "The pipeline is concerning; 1 area is impacted and 1 diagnosis is available",
+          "details": {"status": {"state": "RUNNING"}},
+          "diagnosis": [{
+            "id": "logstash:health:pipeline:flow:worker_utilization:diagnosis:5m-blocked",
+            "cause": "pipeline workers have been completely blocked for at least five minutes",
+            "action": "address bottleneck or add resources",
+            "help_url": "https://ela.st/logstash-pipeline-worker-utilization"
+          }],
+          "impacts": [{"id": "logstash:health:pipeline:flow:impact:blocked_processing", "severity": 2}]
+        },
+        "other": {
+          "status": "GREEN",
+          "symptom": "The pipeline is healthy"
+        }
+      }
+    }
+  }
+}
+`)
+
+func TestHealthReport(t *testing.T) {
+	runCollectorTests(t, NewHealthReportCollector, []collectorTest{
+		{
+			name:      "report",
+			responses: map[string][]byte{"/_health_report": healthReportJSON},
+			expected: map[string]float64{
+				`logstash_health_status{status="green"}`:                                                                                                                    0,
+				`logstash_health_status{status="yellow"}`:                                                                                                                   1,
+				`logstash_health_status{status="red"}`:                                                                                                                      0,
+				`logstash_health_status{status="unknown"}`:                                                                                                                  0,
+				`logstash_health_indicator_status{indicator="pipelines",status="green"}`:                                                                                    0,
+				`logstash_health_indicator_status{indicator="pipelines",status="yellow"}`:                                                                                   1,
+				`logstash_health_indicator_status{indicator="pipelines",status="red"}`:                                                                                      0,
+				`logstash_health_indicator_status{indicator="pipelines",status="unknown"}`:                                                                                  0,
+				`logstash_health_indicator_status{indicator="pipelines/main",status="green"}`:                                                                               0,
+				`logstash_health_indicator_status{indicator="pipelines/main",status="yellow"}`:                                                                              1,
+				`logstash_health_indicator_status{indicator="pipelines/main",status="red"}`:                                                                                 0,
+				`logstash_health_indicator_status{indicator="pipelines/main",status="unknown"}`:                                                                             0,
+				`logstash_health_indicator_status{indicator="pipelines/other",status="green"}`:                                                                              1,
+				`logstash_health_indicator_status{indicator="pipelines/other",status="yellow"}`:                                                                             0,
+				`logstash_health_indicator_status{indicator="pipelines/other",status="red"}`:                                                                                0,
+				`logstash_health_indicator_status{indicator="pipelines/other",status="unknown"}`:                                                                            0,
+				`logstash_health_indicator_diagnosis_info{diagnosis_id="logstash:health:pipeline:flow:worker_utilization:diagnosis:5m-blocked",indicator="pipelines/main"}`: 1,
+			},
+		},
+		{
+			name:      "not supported",
+			responses: map[string][]byte{},
+			absent:    []string{"logstash_"},
+		},
+	})
+}
@@ -0,0 +1,34 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+)
+
+// HotThreadsResponse type
+type HotThreadsResponse struct {
+	HotThreads struct {
+		Time           string      `json:"time"`
+		BusiestThreads int         `json:"busiest_threads"`
+		Threads        []HotThread `json:"threads"`
+	} `json:"hot_threads"`
+}
+
+// HotThread describes one of the busiest threads of a Logstash node
+type HotThread struct {
+	Name             string  `json:"name"`
+	ThreadID         int64   `json:"thread_id"`
+	PercentOfCPUTime float64 `json:"percent_of_cpu_time"`
+	State            string  `json:"state"`
+}
+
+// HotThreads function
+func HotThreads(ctx context.Context, target *Target, threads int) (HotThreadsResponse, error) {
+	var response HotThreadsResponse
+
+	handler := target.handler("hot_threads", fmt.Sprintf("/_node/hot_threads?threads=%d&ignore_idle_threads=true", threads))
+
+	err := getMetrics(ctx, handler, &response)
+
+	return response, err
+}
@@ -0,0 +1,105 @@
+package collector
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"strings"
+)
+
+// DefaultHotThreads is the number of threads reported by the hot threads
+// collector for targets that do not set HotThreads
+const DefaultHotThreads = 10
+
+// HotThreadsCollector type
+type HotThreadsCollector struct {
+	target *Target
+
+	CPUPercent *prometheus.Desc
+	State      *prometheus.Desc
+}
+
+// NewHotThreadsCollector function
+func NewHotThreadsCollector(target *Target) (Collector, error) {
+	const subsystem = "hot_threads"
+
+	return &HotThreadsCollector{
+		target: target,
+
+		CPUPercent: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "cpu_percent"),
+			"Percentage of CPU time used by one of the busiest threads.",
+			[]string{"thread"},
+			nil,
+		),
+
+		State: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "state"),
+			"A metric with a constant '1' value labeled by the state of one of the busiest threads.",
+			[]string{"thread", "state"},
+			nil,
+		),
+	}, nil
+}
+
+// Collect function implements hotthreads_collector collector
+func (c *HotThreadsCollector) Collect(ctx context.Context, ch chan<- prometheus.Metric) error {
+	if desc, err := c.collect(ctx, ch); err != nil {
+		log.Error("Failed collecting hot threads metrics", desc, err)
+		return err
+	}
+	return nil
+}
+
+func (c *HotThreadsCollector) collect(ctx context.Context, ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
+	threads := c.target.HotThreads
+	if threads <= 0 {
+		threads = DefaultHotThreads
+	}
+
+	stats, err := HotThreads(ctx, c.target, threads)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, thread := range stats.HotThreads.Threads {
+		name := threadName(thread.Name)
+		// Threads are sorted by CPU usage, keep the busiest of equally named ones
+		if seen[name] {
+			continue
+		}
+		if len(seen) == threads {
+			break
+		}
+		seen[name] = true
+
+		ch <- prometheus.MustNewConstMetric(
+			c.CPUPercent,
+			prometheus.GaugeValue,
+			thread.PercentOfCPUTime,
+			name,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			c.State,
+			prometheus.GaugeValue,
+			float64(1),
+			name,
+			strings.ToLower(thread.State),
+		)
+	}
+
+	return nil, nil
+}
+
+// threadName normalizes a thread name as reported by Logstash, dropping the
+// source location some versions append, e.g.
+// "Ruby-0-Thread-7: /usr/share/logstash/lib/bootstrap/environment.rb:6" is
+// reported as "Ruby-0-Thread-7", while "[main]>worker3" is kept as is
+func threadName(name string) string {
+	if i := strings.Index(name, ": "); i >= 0 {
+		name = name[:i]
+	}
+	return strings.TrimSpace(name)
+}
@@ -0,0 +1,38 @@
+package collector
+
+import "testing"
+
+func TestHotThreads(t *testing.T) {
+	runCollectorTests(t, NewHotThreadsCollector, []collectorTest{
+		{
+			name:      "hot threads",
+			responses: map[string][]byte{"/_node/hot_threads": readFixture(t, "hot_threads_8.json")},
+			expected: map[string]float64{
+				`logstash_hot_threads_cpu_percent{thread="[main]>worker3"}`:               12.5,
+				`logstash_hot_threads_cpu_percent{thread="[main]<beats"}`:                 1.25,
+				`logstash_hot_threads_cpu_percent{thread="Ruby-0-Thread-7"}`:              0.1,
+				`logstash_hot_threads_state{state="runnable",thread="[main]>worker3"}`:    1,
+				`logstash_hot_threads_state{state="timed_waiting",thread="[main]<beats"}`: 1,
+				`logstash_hot_threads_state{state="waiting",thread="Ruby-0-Thread-7"}`:    1,
+			},
+		},
+	})
+}
+
+func TestHotThreadsCap(t *testing.T) {
+	newCollector := func(target *Target) (Collector, error) {
+		target.HotThreads = 1
+		return NewHotThreadsCollector(target)
+	}
+
+	runCollectorTests(t, newCollector, []collectorTest{
+		{
+			name:      "one thread",
+			responses: map[string][]byte{"/_node/hot_threads": readFixture(t, "hot_threads_8.json")},
+			expected: map[string]float64{
+				`logstash_hot_threads_cpu_percent{thread="[main]>worker3"}`:            12.5,
+				`logstash_hot_threads_state{state="runnable",thread="[main]>worker3"}`: 1,
+			},
+		},
+	})
+}
@@ -0,0 +1,57 @@
+package collector
+
+import (
+	"context"
+	"github.com/prometheus/common/log"
+	"strings"
+)
+
+// gcGenerations maps the names of the garbage collectors of HotSpot JVMs to
+// the generation Logstash reports their node stats under
+var gcGenerations = map[string]string{
+	"Copy":                "young",
+	"PS Scavenge":         "young",
+	"ParNew":              "young",
+	"G1 Young Generation": "young",
+	"ZGC Minor Cycles":    "young",
+	"ZGC Minor Pauses":    "young",
+	"MarkSweepCompact":    "old",
+	"PS MarkSweep":        "old",
+	"ConcurrentMarkSweep": "old",
+	"G1 Old Generation":   "old",
+	"G1 Concurrent GC":    "old",
+	"ZGC Major Cycles":    "old",
+	"ZGC Major Pauses":    "old",
+	"ZGC Cycles":          "old",
+	"ZGC Pauses":          "old",
+	"Shenandoah Cycles":   "old",
+	"Shenandoah Pauses":   "old",
+}
+
+// gcCollectorNames maps the garbage collectors in node stats, e.g. young,
+// to the names the JVM reports in node info, e.g. G1 Young Generation. An
+// empty map is returned if node info is unavailable.
+func (t *Target) gcCollectorNames(ctx context.Context, uptimeInMillis int64) map[string]string {
+	info, err := t.nodeInfo(ctx, uptimeInMillis)
+	if err != nil {
+		log.Debugf("Cannot retrieve garbage collector names: %v", err)
+		return map[string]string{}
+	}
+
+	generations := make(map[string][]string)
+	for _, name := range info.Jvm.GcCollectors {
+		generation, ok := gcGenerations[name]
+		if !ok {
+			// Collectors Logstash reports under their own name
+			generation = name
+		}
+		generations[generation] = append(generations[generation], name)
+	}
+
+	names := make(map[string]string, len(generations))
+	for generation, jvmNames := range generations {
+		names[generation] = strings.Join(jvmNames, ", ")
+	}
+
+	return names
+}
@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// KeyFilter selects plugin-specific metrics by key, e.g.
+// patterns_per_field.message. Keys must fully match one of the allowed
+// regular expressions, if any, and none of the denied ones.
+type KeyFilter struct {
+	allow []*regexp.Regexp
+	deny  []*regexp.Regexp
+}
+
+// NewKeyFilter compiles the allowed and denied key regular expressions
+func NewKeyFilter(allow, deny []string) (*KeyFilter, error) {
+	f := &KeyFilter{}
+
+	for _, expr := range allow {
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid allow regexp %q: %v", expr, err)
+		}
+		f.allow = append(f.allow, re)
+	}
+
+	for _, expr := range deny {
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid deny regexp %q: %v", expr, err)
+		}
+		f.deny = append(f.deny, re)
+	}
+
+	return f, nil
+}
+
+// Match reports whether key is selected. A nil KeyFilter selects all keys.
+func (f *KeyFilter) Match(key string) bool {
+	if f == nil {
+		return true
+	}
+
+	for _, re := range f.deny {
+		if re.MatchString(key) {
+			return false
+		}
+	}
+
+	if len(f.allow) == 0 {
+		return true
+	}
+	for _, re := range f.allow {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,31 @@
+package collector
+
+import "testing"
+
+func TestKeyFilter(t *testing.T) {
+	f, err := NewKeyFilter([]string{"current_connections", "patterns_per_field\\..*"}, []string{"patterns_per_field\\.debug"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, expected := range map[string]bool{
+		"current_connections":        true,
+		"peak_current_connections":   false,
+		"patterns_per_field.message": true,
+		"patterns_per_field.debug":   false,
+		"matches":                    false,
+	} {
+		if actual := f.Match(key); actual != expected {
+			t.Errorf("Match(%q) = %v, expected %v", key, actual, expected)
+		}
+	}
+
+	var all *KeyFilter
+	if !all.Match("anything") {
+		t.Error("expected a nil KeyFilter to match all keys")
+	}
+
+	if _, err := NewKeyFilter(nil, []string{"("}); err == nil {
+		t.Error("expected an error for an invalid regexp")
+	}
+}
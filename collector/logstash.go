@@ -1,6 +1,9 @@
 package collector
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+)
 
 const (
 	// Namespace const string
@@ -9,5 +12,5 @@ const (
 
 // Collector interface implement Collect function
 type Collector interface {
-	Collect(ch chan<- prometheus.Metric) (err error)
+	Collect(ctx context.Context, ch chan<- prometheus.Metric) (err error)
 }
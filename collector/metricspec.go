@@ -0,0 +1,251 @@
+package collector
+
+import (
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// unit is the unit Logstash reports a stat in. Durations are converted to
+// seconds and timestamps to Unix time.
+type unit int
+
+const (
+	unitNone unit = iota
+	unitMillis
+	unitMicros
+	unitNanos
+	unitTimestamp
+)
+
+var unitNames = map[unit]string{
+	unitMillis:    "milliseconds",
+	unitMicros:    "microseconds",
+	unitNanos:     "nanoseconds",
+	unitTimestamp: "RFC 3339 timestamp",
+}
+
+// convert returns the value of a stat in the base unit of its metric. It
+// fails for nil pointers and timestamps that are unset or invalid.
+func (u unit) convert(v reflect.Value) (float64, bool) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return 0, false
+		}
+		v = v.Elem()
+	}
+
+	var value float64
+	switch v.Kind() {
+	case reflect.Int, reflect.Int64:
+		value = float64(v.Int())
+	case reflect.Float64:
+		value = v.Float()
+	case reflect.String:
+		s := v.String()
+		return parseTimestamp(&s)
+	default:
+		return 0, false
+	}
+
+	switch u {
+	case unitMillis:
+		value /= 1e3
+	case unitMicros:
+		value /= 1e6
+	case unitNanos:
+		value /= 1e9
+	}
+	return value, true
+}
+
+// metricScope is a kind of object in node stats that metrics are read from,
+// such as a pipeline, each identified by the values of labels
+type metricScope struct {
+	path   string // in node stats, for the metric reference
+	labels []string
+	typ    reflect.Type
+}
+
+// scopedValue is an object in node stats and its label values
+type scopedValue struct {
+	labels []string
+	value  interface{}
+}
+
+// metricSpec maps a stat of the objects of a scope to a metric
+type metricSpec struct {
+	scope       *metricScope
+	path        string // dotted JSON path of the stat below the scope
+	unit        unit
+	name        string
+	help        string
+	valueType   prometheus.ValueType
+	pluginTypes []string // of the plugins reporting the stat, all if empty
+	positive    bool     // if negative values mean the stat is unset
+}
+
+// metricDef describes a metric for its desc and the metric reference
+type metricDef struct {
+	name      string
+	help      string
+	valueType prometheus.ValueType
+	labels    []string
+	source    string // the stat the metric is read from
+	unit      unit
+}
+
+func (def metricDef) desc(subsystem string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, subsystem, def.name),
+		def.help,
+		def.labels,
+		nil,
+	)
+}
+
+func (spec metricSpec) def() metricDef {
+	source := spec.path
+	if spec.scope.path != "" {
+		source = spec.scope.path + "." + spec.path
+	}
+	return metricDef{
+		name:      spec.name,
+		help:      spec.help,
+		valueType: spec.valueType,
+		labels:    spec.scope.labels,
+		source:    source,
+		unit:      spec.unit,
+	}
+}
+
+// specMetric is a metricSpec with its desc, and the stat's path compiled to
+// field indexes
+type specMetric struct {
+	metricSpec
+	desc  *prometheus.Desc
+	index []int
+}
+
+func newSpecMetrics(subsystem string, specs []metricSpec) ([]specMetric, error) {
+	metrics := make([]specMetric, len(specs))
+	for i, spec := range specs {
+		index, err := fieldIndex(spec.scope.typ, spec.path)
+		if err != nil {
+			return nil, fmt.Errorf("metric %s: %v", spec.name, err)
+		}
+		metrics[i] = specMetric{spec, spec.def().desc(subsystem), index}
+	}
+	return metrics, nil
+}
+
+// fieldIndex returns the indexes of the struct fields along a dotted path of
+// JSON field names below typ
+func fieldIndex(typ reflect.Type, path string) ([]int, error) {
+	var index []int
+	for _, name := range strings.Split(path, ".") {
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		if typ.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%s: %s is not an object", path, name)
+		}
+
+		field, ok := jsonField(typ, name)
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown field %s", path, name)
+		}
+		index, typ = append(index, field.Index...), field.Type
+	}
+	return index, nil
+}
+
+func jsonField(typ reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if strings.Split(field.Tag.Get("json"), ",")[0] == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// collect sends the metric for each object of the spec's scope that reports
+// its stat
+func (m specMetric) collect(ch chan<- prometheus.Metric, objects []scopedValue) {
+	for _, object := range objects {
+		// The plugin type is the last label of the plugin scope
+		if len(m.pluginTypes) > 0 && !contains(m.pluginTypes, object.labels[len(object.labels)-1]) {
+			continue
+		}
+
+		v, ok := lookup(reflect.ValueOf(object.value), m.index)
+		if !ok {
+			continue
+		}
+		value, ok := m.unit.convert(v)
+		if !ok || (m.positive && value < 0) {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, value, object.labels...)
+	}
+}
+
+// lookup follows the field indexes of a path below v. It fails if a pointer
+// on the way is nil.
+func lookup(v reflect.Value, index []int) (reflect.Value, bool) {
+	for _, i := range index {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return v, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v, true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+var valueTypeNames = map[prometheus.ValueType]string{
+	prometheus.CounterValue: "counter",
+	prometheus.GaugeValue:   "gauge",
+	prometheus.UntypedValue: "untyped",
+}
+
+// writeMetricReference writes a Markdown table of defs, sorted by name
+func writeMetricReference(w io.Writer, subsystem string, defs []metricDef) error {
+	sort.Slice(defs, func(i, j int) bool { return defs[i].name < defs[j].name })
+
+	if _, err := fmt.Fprint(w, "| Metric | Type | Labels | Source | Help |\n| --- | --- | --- | --- | --- |\n"); err != nil {
+		return err
+	}
+	for _, def := range defs {
+		source := "`" + def.source + "`"
+		if name, ok := unitNames[def.unit]; ok {
+			source += " (" + name + ")"
+		}
+		if _, err := fmt.Fprintf(w, "| `%s` | %s | %s | %s | %s |\n",
+			prometheus.BuildFQName(Namespace, subsystem, def.name),
+			valueTypeNames[def.valueType],
+			strings.Join(def.labels, ", "),
+			source,
+			def.help,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
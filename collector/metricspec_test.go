@@ -0,0 +1,91 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"github.com/prometheus/client_golang/prometheus"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update METRICS.md")
+
+const metricReferenceHeader = `# Node metrics
+Metrics of the node collector, generated from its metric specs with
+` + "`make docs`" + `. Sources are paths in the ` + "`/_node/stats`" + ` API of Logstash.
+
+`
+
+func TestNodeMetricReference(t *testing.T) {
+	var reference bytes.Buffer
+	reference.WriteString(metricReferenceHeader)
+	if err := WriteNodeMetricReference(&reference); err != nil {
+		t.Fatal(err)
+	}
+
+	if *update {
+		if err := ioutil.WriteFile("../METRICS.md", reference.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	content, err := ioutil.ReadFile("../METRICS.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, reference.Bytes()) {
+		t.Error("METRICS.md is out of date, run make docs")
+	}
+}
+
+func TestFieldIndex(t *testing.T) {
+	typ := reflect.TypeOf(NodeStatsResponse{})
+
+	if _, err := fieldIndex(typ, "process.cpu.load_average.1m"); err != nil {
+		t.Errorf("expected a field index through pointers, got %v", err)
+	}
+	if _, err := fieldIndex(typ, "process.cpu.idle"); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+	if _, err := fieldIndex(typ, "version.major"); err == nil {
+		t.Error("expected an error for a path below a string")
+	}
+}
+
+func TestNodeStatsDescribe(t *testing.T) {
+	stats := readFixture(t, "node_stats_8.json")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(stats)
+	}))
+	defer server.Close()
+
+	c, err := NewNodeStatsCollector(NewTarget(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	descs := make(chan *prometheus.Desc)
+	go func() {
+		c.(*NodeStatsCollector).Describe(descs)
+		close(descs)
+	}()
+	described := make(map[string]bool)
+	for desc := range descs {
+		described[desc.String()] = true
+	}
+
+	metrics := make(chan prometheus.Metric)
+	go func() {
+		c.Collect(context.Background(), metrics)
+		close(metrics)
+	}()
+	for metric := range metrics {
+		if !described[metric.Desc().String()] {
+			t.Errorf("expected %s to be described", metric.Desc())
+		}
+	}
+}
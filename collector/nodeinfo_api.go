@@ -1,5 +1,7 @@
 package collector
 
+import "context"
+
 // NodeInfoResponse type
 type NodeInfoResponse struct {
 	Host        string `json:"host"`
@@ -38,14 +40,47 @@ type NodeInfoResponse struct {
 }
 
 // NodeInfo function
-func NodeInfo(endpoint string) (NodeInfoResponse, error) {
+func NodeInfo(ctx context.Context, target *Target) (NodeInfoResponse, error) {
 	var response NodeInfoResponse
 
-	handler := &HTTPHandler{
-		Endpoint: endpoint + "/_node",
-	}
+	handler := target.handler("node_info", "/_node")
 
-	err := getMetrics(handler, &response)
+	err := getMetrics(ctx, handler, &response)
 
 	return response, err
 }
+
+// nodeInfo returns the node info of target. It is fetched once per JVM,
+// detected by its uptime, as neither the Logstash version nor the JVM
+// settings change without a restart.
+func (t *Target) nodeInfo(ctx context.Context, uptimeInMillis int64) (NodeInfoResponse, error) {
+	t.mtx.Lock()
+	t.observeUptime(uptimeInMillis)
+	if t.info != nil {
+		info := *t.info
+		t.mtx.Unlock()
+		return info, nil
+	}
+	t.mtx.Unlock()
+
+	info, err := NodeInfo(ctx, t)
+	if err != nil {
+		return info, err
+	}
+
+	t.mtx.Lock()
+	t.info = &info
+	t.mtx.Unlock()
+
+	return info, nil
+}
+
+// observeUptime records the last JVM uptime seen in node stats, and drops
+// what is cached per JVM if the uptime went down, as Logstash restarted. The
+// caller must hold t.mtx.
+func (t *Target) observeUptime(uptimeInMillis int64) {
+	if uptimeInMillis < t.uptime {
+		t.info, t.version = nil, ""
+	}
+	t.uptime = uptimeInMillis
+}
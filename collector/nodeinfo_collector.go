@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
 	"strconv"
@@ -8,7 +9,7 @@ import (
 
 // NodeInfoCollector type
 type NodeInfoCollector struct {
-	endpoint string
+	target *Target
 
 	NodeInfos *prometheus.Desc
 	OsInfos   *prometheus.Desc
@@ -16,11 +17,11 @@ type NodeInfoCollector struct {
 }
 
 // NewNodeInfoCollector function
-func NewNodeInfoCollector(logstashEndpoint string) (Collector, error) {
+func NewNodeInfoCollector(target *Target) (Collector, error) {
 	const subsystem = "info"
 
 	return &NodeInfoCollector{
-		endpoint: logstashEndpoint,
+		target: target,
 
 		NodeInfos: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "node"),
@@ -46,16 +47,16 @@ func NewNodeInfoCollector(logstashEndpoint string) (Collector, error) {
 }
 
 // Collect function implements nodestats_collector collector
-func (c *NodeInfoCollector) Collect(ch chan<- prometheus.Metric) error {
-	if desc, err := c.collect(ch); err != nil {
+func (c *NodeInfoCollector) Collect(ctx context.Context, ch chan<- prometheus.Metric) error {
+	if desc, err := c.collect(ctx, ch); err != nil {
 		log.Error("Failed collecting info metrics", desc, err)
 		return err
 	}
 	return nil
 }
 
-func (c *NodeInfoCollector) collect(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
-	stats, err := NodeInfo(c.endpoint)
+func (c *NodeInfoCollector) collect(ctx context.Context, ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
+	stats, err := NodeInfo(ctx, c.target)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,36 @@
+package collector
+
+import "context"
+
+// NodePipelinesResponse type
+type NodePipelinesResponse struct {
+	Host      string                  `json:"host"`
+	Version   string                  `json:"version"`
+	Pipelines map[string]PipelineInfo `json:"pipelines"` // Logstash >=6
+}
+
+// PipelineInfo holds the settings of a pipeline. Settings a Logstash version
+// does not report are left empty.
+type PipelineInfo struct {
+	EphemeralID            string      `json:"ephemeral_id"`
+	Hash                   string      `json:"hash"`
+	Workers                int         `json:"workers"`
+	BatchSize              int         `json:"batch_size"`
+	BatchDelay             int         `json:"batch_delay"` // milliseconds
+	ConfigReloadAutomatic  bool        `json:"config_reload_automatic"`
+	DeadLetterQueueEnabled bool        `json:"dead_letter_queue_enabled"`
+	DeadLetterQueuePath    string      `json:"dead_letter_queue_path"`
+	Ordered                interface{} `json:"ordered"`           // "auto", true or false
+	ECSCompatibility       interface{} `json:"ecs_compatibility"` // "disabled", "v1", "v8"
+}
+
+// NodePipelines function
+func NodePipelines(ctx context.Context, target *Target) (NodePipelinesResponse, error) {
+	var response NodePipelinesResponse
+
+	handler := target.handler("pipelines", "/_node/pipelines")
+
+	err := getMetrics(ctx, handler, &response)
+
+	return response, err
+}
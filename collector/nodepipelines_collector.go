@@ -0,0 +1,119 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"strconv"
+)
+
+// NodePipelinesCollector type
+type NodePipelinesCollector struct {
+	target *Target
+
+	PipelineInfos      *prometheus.Desc
+	PipelineWorkers    *prometheus.Desc
+	PipelineBatchSize  *prometheus.Desc
+	PipelineBatchDelay *prometheus.Desc
+}
+
+// NewNodePipelinesCollector function
+func NewNodePipelinesCollector(target *Target) (Collector, error) {
+	const subsystem = "pipeline"
+
+	return &NodePipelinesCollector{
+		target: target,
+
+		PipelineInfos: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "info"),
+			"A metric with a constant '1' value labeled by the non-numeric settings of a pipeline.",
+			[]string{"pipeline", "hash", "ordered", "ecs_compatibility", "config_reload_automatic", "dead_letter_queue_enabled", "dead_letter_queue_path"},
+			nil,
+		),
+
+		PipelineWorkers: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "workers"),
+			"Number of workers of a pipeline.",
+			[]string{"pipeline"},
+			nil,
+		),
+
+		PipelineBatchSize: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "batch_size"),
+			"Maximum number of events a worker of a pipeline collects per batch.",
+			[]string{"pipeline"},
+			nil,
+		),
+
+		PipelineBatchDelay: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "batch_delay_seconds"),
+			"Maximum time a worker of a pipeline waits for a batch to fill up.",
+			[]string{"pipeline"},
+			nil,
+		),
+	}, nil
+}
+
+// Collect function implements nodepipelines_collector collector
+func (c *NodePipelinesCollector) Collect(ctx context.Context, ch chan<- prometheus.Metric) error {
+	if desc, err := c.collect(ctx, ch); err != nil {
+		log.Error("Failed collecting pipelines metrics", desc, err)
+		return err
+	}
+	return nil
+}
+
+func (c *NodePipelinesCollector) collect(ctx context.Context, ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
+	stats, err := NodePipelines(ctx, c.target)
+	if err != nil {
+		return nil, err
+	}
+
+	for pipelineID, pipeline := range stats.Pipelines {
+		ch <- prometheus.MustNewConstMetric(
+			c.PipelineInfos,
+			prometheus.GaugeValue,
+			float64(1),
+			pipelineID,
+			pipeline.Hash,
+			settingValue(pipeline.Ordered),
+			settingValue(pipeline.ECSCompatibility),
+			strconv.FormatBool(pipeline.ConfigReloadAutomatic),
+			strconv.FormatBool(pipeline.DeadLetterQueueEnabled),
+			pipeline.DeadLetterQueuePath,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			c.PipelineWorkers,
+			prometheus.GaugeValue,
+			float64(pipeline.Workers),
+			pipelineID,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			c.PipelineBatchSize,
+			prometheus.GaugeValue,
+			float64(pipeline.BatchSize),
+			pipelineID,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			c.PipelineBatchDelay,
+			prometheus.GaugeValue,
+			float64(pipeline.BatchDelay)/1000,
+			pipelineID,
+		)
+	}
+
+	return nil, nil
+}
+
+// settingValue formats a setting Logstash reports as either a string or a
+// boolean, or an empty string if it is not reported
+func settingValue(setting interface{}) string {
+	if setting == nil {
+		return ""
+	}
+	return fmt.Sprint(setting)
+}
@@ -0,0 +1,54 @@
+package collector
+
+import "testing"
+
+var nodePipelinesJSON = []byte(`
+{
+  "host": "logstash-1",
+  "version": "8.6.0",
+  "pipelines": {
+    "main": {
+      "ephemeral_id": "0e4a3a2c-6f2b-4c2f-9d43-6f3e0b1c1f5e",
+      "hash": "b2a1c3d4",
+      "workers": 8,
+      "batch_size": 125,
+      "batch_delay": 50,
+      "config_reload_automatic": true,
+      "config_reload_interval": 3000000000,
+      "dead_letter_queue_enabled": true,
+      "dead_letter_queue_path": "/var/lib/logstash/dead_letter_queue/main",
+      "ordered": "auto",
+      "ecs_compatibility": "v8"
+    },
+    "ordered": {
+      "ephemeral_id": "5c1f0c4e-2f0a-4a53-8bd2-3b8d1c0e7a11",
+      "hash": "e5f6a7b8",
+      "workers": 1,
+      "batch_size": 500,
+      "batch_delay": 5,
+      "config_reload_automatic": false,
+      "dead_letter_queue_enabled": false,
+      "ordered": true
+    }
+  }
+}
+`)
+
+func TestNodePipelines(t *testing.T) {
+	runCollectorTests(t, NewNodePipelinesCollector, []collectorTest{
+		{
+			name:      "pipelines",
+			responses: map[string][]byte{"/_node/pipelines": nodePipelinesJSON},
+			expected: map[string]float64{
+				`logstash_pipeline_workers{pipeline="main"}`:                8,
+				`logstash_pipeline_batch_size{pipeline="main"}`:             125,
+				`logstash_pipeline_batch_delay_seconds{pipeline="main"}`:    0.05,
+				`logstash_pipeline_workers{pipeline="ordered"}`:             1,
+				`logstash_pipeline_batch_size{pipeline="ordered"}`:          500,
+				`logstash_pipeline_batch_delay_seconds{pipeline="ordered"}`: 0.005,
+				`logstash_pipeline_info{config_reload_automatic="true",dead_letter_queue_enabled="true",dead_letter_queue_path="/var/lib/logstash/dead_letter_queue/main",ecs_compatibility="v8",hash="b2a1c3d4",ordered="auto",pipeline="main"}`: 1,
+				`logstash_pipeline_info{config_reload_automatic="false",dead_letter_queue_enabled="false",dead_letter_queue_path="",ecs_compatibility="",hash="e5f6a7b8",ordered="true",pipeline="ordered"}`:                                      1,
+			},
+		},
+	})
+}
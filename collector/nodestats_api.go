@@ -1,70 +1,176 @@
 package collector
 
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+)
+
+// FlowMetrics holds Logstash 8.5+ flow metrics, keyed by metric and window
+// (current, last_1_minute, ..., lifetime)
+type FlowMetrics map[string]map[string]float64
+
+// Reloads holds config reload statistics. Only successes and failures are
+// reported at node level.
+type Reloads struct {
+	LastError            *ReloadError `json:"last_error"`
+	Successes            int          `json:"successes"`
+	LastSuccessTimestamp *string      `json:"last_success_timestamp"`
+	LastFailureTimestamp *string      `json:"last_failure_timestamp"`
+	Failures             int          `json:"failures"`
+}
+
+// ReloadError is the error of the last failed config reload
+type ReloadError struct {
+	Message   string   `json:"message"`
+	Backtrace []string `json:"backtrace"`
+}
+
+// Queue holds the stats of a pipeline's queue. Logstash 5.x and 6.x report
+// the queue size under capacity and the number of events as events, which
+// their schema adapters move to where Logstash >=7 reports them.
+type Queue struct {
+	Events              int64  `json:"events"`
+	EventsCount         *int64 `json:"events_count"`
+	Type                string `json:"type"`
+	QueueSizeInBytes    *int64 `json:"queue_size_in_bytes"`
+	MaxQueueSizeInBytes *int64 `json:"max_queue_size_in_bytes"`
+	Capacity            struct {
+		PageCapacityInBytes int64  `json:"page_capacity_in_bytes"`
+		MaxQueueSizeInBytes int64  `json:"max_queue_size_in_bytes"`
+		MaxUnreadEvents     int64  `json:"max_unread_events"`
+		QueueSizeInBytes    *int64 `json:"queue_size_in_bytes"` // Logstash 6.x
+	} `json:"capacity"`
+	Data QueueData `json:"data"`
+}
+
+// QueueData holds the filesystem a persisted queue is stored on
+type QueueData struct {
+	Path             string `json:"path"`
+	FreeSpaceInBytes int64  `json:"free_space_in_bytes"`
+	StorageType      string `json:"storage_type"`
+}
+
+// DeadLetterQueue holds the stats of a pipeline's dead letter queue. All but
+// the queue size are only reported by Logstash >=8.
+type DeadLetterQueue struct {
+	QueueSizeInBytes    int64   `json:"queue_size_in_bytes"`
+	MaxQueueSizeInBytes *int64  `json:"max_queue_size_in_bytes"`
+	DroppedEvents       *int64  `json:"dropped_events"`
+	ExpiredEvents       *int64  `json:"expired_events"`
+	LastError           *string `json:"last_error"`
+	StoragePolicy       *string `json:"storage_policy"`
+}
+
+// MemPool holds the usage of a JVM memory pool
+type MemPool struct {
+	PeakUsedInBytes  int64 `json:"peak_used_in_bytes"`
+	UsedInBytes      int64 `json:"used_in_bytes"`
+	PeakMaxInBytes   int64 `json:"peak_max_in_bytes"`
+	MaxInBytes       int64 `json:"max_in_bytes"`
+	CommittedInBytes int64 `json:"committed_in_bytes"`
+}
+
+// GCCollector holds the activity of a JVM garbage collector
+type GCCollector struct {
+	CollectionTimeInMillis int64 `json:"collection_time_in_millis"`
+	CollectionCount        int64 `json:"collection_count"`
+}
+
+// CgroupCPUAcct holds the CPU usage of the cgroup of Logstash
+type CgroupCPUAcct struct {
+	ControlGroup string `json:"control_group"`
+	UsageNanos   int64  `json:"usage_nanos"`
+}
+
+// CgroupCPU holds the CFS bandwidth control of the cgroup of Logstash
+type CgroupCPU struct {
+	ControlGroup    string `json:"control_group"`
+	CFSPeriodMicros int64  `json:"cfs_period_micros"`
+	CFSQuotaMicros  int64  `json:"cfs_quota_micros"` // -1 if unlimited
+	Stat            struct {
+		NumberOfElapsedPeriods int64 `json:"number_of_elapsed_periods"`
+		NumberOfTimesThrottled int64 `json:"number_of_times_throttled"`
+		TimeThrottledNanos     int64 `json:"time_throttled_nanos"`
+	} `json:"stat"`
+}
+
+// Plugin holds the stats of a plugin of a pipeline
+type Plugin struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Events struct {
+		DurationInMillis          int   `json:"duration_in_millis"`
+		In                        int   `json:"in"`
+		Out                       int   `json:"out"`
+		QueuePushDurationInMillis int64 `json:"queue_push_duration_in_millis"`
+	} `json:"events"`
+	Matches  int         `json:"matches,omitempty"`
+	Failures int         `json:"failures,omitempty"`
+	Flow     FlowMetrics `json:"flow"`
+	// Metrics holds every other numeric stat the plugin reports, keyed by
+	// its dotted path, e.g. patterns_per_field.message
+	Metrics map[string]float64 `json:"-"`
+}
+
+var typeOfPlugin = reflect.TypeOf(Plugin{})
+
+// UnmarshalJSON decodes the well-known stats of a plugin, and collects the
+// plugin-specific ones in Metrics. Stats decoded into fields of Plugin are
+// left out of Metrics, as they are exported as typed metrics.
+func (p *Plugin) UnmarshalJSON(data []byte) error {
+	type plugin Plugin
+	if err := json.Unmarshal(data, (*plugin)(p)); err != nil {
+		return err
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	p.Metrics = make(map[string]float64)
+	for key, value := range raw {
+		if _, ok := jsonField(typeOfPlugin, key); ok {
+			continue
+		}
+		flatten(p.Metrics, key, value)
+	}
+
+	return nil
+}
+
+// flatten adds the numeric leaves of value to metrics, keyed by their path
+// below prefix
+func flatten(metrics map[string]float64, prefix string, value interface{}) {
+	switch v := value.(type) {
+	case float64:
+		metrics[prefix] = v
+	case map[string]interface{}:
+		for key, child := range v {
+			flatten(metrics, prefix+"."+key, child)
+		}
+	}
+}
+
 // Pipeline type
 type Pipeline struct {
 	Events struct {
-		DurationInMillis int `json:"duration_in_millis"`
-		In               int `json:"in"`
-		Filtered         int `json:"filtered"`
-		Out              int `json:"out"`
+		DurationInMillis          int   `json:"duration_in_millis"`
+		In                        int   `json:"in"`
+		Filtered                  int   `json:"filtered"`
+		Out                       int   `json:"out"`
+		QueuePushDurationInMillis int64 `json:"queue_push_duration_in_millis"`
 	} `json:"events"`
 	Plugins struct {
-		Inputs []struct {
-			ID     string `json:"id"`
-			Events struct {
-				In  int `json:"in"`
-				Out int `json:"out"`
-			} `json:"events"`
-			Name string `json:"name"`
-		} `json:"inputs,omitempty"`
-		Filters []struct {
-			ID     string `json:"id"`
-			Events struct {
-				DurationInMillis int `json:"duration_in_millis"`
-				In               int `json:"in"`
-				Out              int `json:"out"`
-			} `json:"events,omitempty"`
-			Name             string `json:"name"`
-			Matches          int    `json:"matches,omitempty"`
-			Failures         int    `json:"failures,omitempty"`
-			PatternsPerField struct {
-				CapturedRequestHeaders int `json:"captured_request_headers"`
-			} `json:"patterns_per_field,omitempty"`
-			Formats int `json:"formats,omitempty"`
-		} `json:"filters"`
-		Outputs []struct {
-			ID     string `json:"id"`
-			Events struct {
-				In  int `json:"in"`
-				Out int `json:"out"`
-			} `json:"events"`
-			Name string `json:"name"`
-		} `json:"outputs"`
+		Inputs  []Plugin `json:"inputs,omitempty"`
+		Filters []Plugin `json:"filters"`
+		Outputs []Plugin `json:"outputs"`
 	} `json:"plugins"`
-	Reloads struct {
-		LastError            interface{} `json:"last_error"`
-		Successes            int         `json:"successes"`
-		LastSuccessTimestamp interface{} `json:"last_success_timestamp"`
-		LastFailureTimestamp interface{} `json:"last_failure_timestamp"`
-		Failures             int         `json:"failures"`
-	} `json:"reloads"`
-	Queue struct {
-		Events   int    `json:"events"`
-		Type     string `json:"type"`
-		Capacity struct {
-			PageCapacityInBytes int   `json:"page_capacity_in_bytes"`
-			MaxQueueSizeInBytes int64 `json:"max_queue_size_in_bytes"`
-			MaxUnreadEvents     int   `json:"max_unread_events"`
-		} `json:"capacity"`
-		Data struct {
-			Path             string `json:"path"`
-			FreeSpaceInBytes int64  `json:"free_space_in_bytes"`
-			StorageType      string `json:"storage_type"`
-		} `json:"data"`
-	} `json:"queue"`
-	DeadLetterQueue struct {
-		QueueSizeInBytes int `json:"queue_size_in_bytes"`
-	} `json:"dead_letter_queue"`
+	Reloads         Reloads          `json:"reloads"`
+	Queue           Queue            `json:"queue"`
+	DeadLetterQueue *DeadLetterQueue `json:"dead_letter_queue"` // nil if disabled
+	Flow            FlowMetrics      `json:"flow"`              // Logstash >=8.5
 }
 
 // NodeStatsResponse type
@@ -78,48 +184,18 @@ type NodeStatsResponse struct {
 			PeakCount int `json:"peak_count"`
 		} `json:"threads"`
 		Mem struct {
-			HeapUsedInBytes         int `json:"heap_used_in_bytes"`
-			HeapUsedPercent         int `json:"heap_used_percent"`
-			HeapCommittedInBytes    int `json:"heap_committed_in_bytes"`
-			HeapMaxInBytes          int `json:"heap_max_in_bytes"`
-			NonHeapUsedInBytes      int `json:"non_heap_used_in_bytes"`
-			NonHeapCommittedInBytes int `json:"non_heap_committed_in_bytes"`
-			Pools                   struct {
-				Survivor struct {
-					PeakUsedInBytes  int `json:"peak_used_in_bytes"`
-					UsedInBytes      int `json:"used_in_bytes"`
-					PeakMaxInBytes   int `json:"peak_max_in_bytes"`
-					MaxInBytes       int `json:"max_in_bytes"`
-					CommittedInBytes int `json:"committed_in_bytes"`
-				} `json:"survivor"`
-				Old struct {
-					PeakUsedInBytes  int `json:"peak_used_in_bytes"`
-					UsedInBytes      int `json:"used_in_bytes"`
-					PeakMaxInBytes   int `json:"peak_max_in_bytes"`
-					MaxInBytes       int `json:"max_in_bytes"`
-					CommittedInBytes int `json:"committed_in_bytes"`
-				} `json:"old"`
-				Young struct {
-					PeakUsedInBytes  int `json:"peak_used_in_bytes"`
-					UsedInBytes      int `json:"used_in_bytes"`
-					PeakMaxInBytes   int `json:"peak_max_in_bytes"`
-					MaxInBytes       int `json:"max_in_bytes"`
-					CommittedInBytes int `json:"committed_in_bytes"`
-				} `json:"young"`
-			} `json:"pools"`
+			HeapUsedInBytes         int                `json:"heap_used_in_bytes"`
+			HeapUsedPercent         int                `json:"heap_used_percent"`
+			HeapCommittedInBytes    int                `json:"heap_committed_in_bytes"`
+			HeapMaxInBytes          int                `json:"heap_max_in_bytes"`
+			NonHeapUsedInBytes      int                `json:"non_heap_used_in_bytes"`
+			NonHeapCommittedInBytes int                `json:"non_heap_committed_in_bytes"`
+			Pools                   map[string]MemPool `json:"pools"` // e.g. young, old and survivor
 		} `json:"mem"`
 		Gc struct {
-			Collectors struct {
-				Old struct {
-					CollectionTimeInMillis int `json:"collection_time_in_millis"`
-					CollectionCount        int `json:"collection_count"`
-				} `json:"old"`
-				Young struct {
-					CollectionTimeInMillis int `json:"collection_time_in_millis"`
-					CollectionCount        int `json:"collection_count"`
-				} `json:"young"`
-			} `json:"collectors"`
+			Collectors map[string]GCCollector `json:"collectors"` // e.g. young and old
 		} `json:"gc"`
+		UptimeInMillis int64 `json:"uptime_in_millis"`
 	} `json:"jvm"`
 	Process struct {
 		OpenFileDescriptors     int `json:"open_file_descriptors"`
@@ -131,21 +207,62 @@ type NodeStatsResponse struct {
 		CPU struct {
 			TotalInMillis int64 `json:"total_in_millis"`
 			Percent       int   `json:"percent"`
+			LoadAverage   *struct {
+				OneMinute      *float64 `json:"1m"`
+				FiveMinutes    *float64 `json:"5m"`
+				FifteenMinutes *float64 `json:"15m"`
+			} `json:"load_average"` // not reported on Windows
 		} `json:"cpu"`
 	} `json:"process"`
-	Pipeline  Pipeline            `json:"pipeline"`  // Logstash 5
+	Os struct {
+		Cgroup *struct {
+			CPUAcct CgroupCPUAcct `json:"cpuacct"`
+			CPU     CgroupCPU     `json:"cpu"`
+		} `json:"cgroup"` // Linux only
+	} `json:"os"`
+	Pipeline  Pipeline            `json:"pipeline"`  // Logstash 5, moved to pipelines
 	Pipelines map[string]Pipeline `json:"pipelines"` // Logstash >=6
+	Flow      FlowMetrics         `json:"flow"`      // Logstash >=8.5
+	Reloads   Reloads             `json:"reloads"`
+	Events    *struct {
+		DurationInMillis          int64 `json:"duration_in_millis"`
+		In                        int64 `json:"in"`
+		Filtered                  int64 `json:"filtered"`
+		Out                       int64 `json:"out"`
+		QueuePushDurationInMillis int64 `json:"queue_push_duration_in_millis"`
+	} `json:"events"` // Logstash >=6
+	Queue struct {
+		EventsCount *int64 `json:"events_count"`
+	} `json:"queue"` // Logstash >=7
+	// Schema is the name of the adapter the stats were normalized by, and
+	// SchemaVersion the Logstash version it was chosen for
+	Schema        string `json:"-"`
+	SchemaVersion string `json:"-"`
 }
 
-// NodeStats function
-func NodeStats(endpoint string) (NodeStatsResponse, error) {
+// NodeStats fetches the node stats of target, normalized by the schema
+// adapter for its Logstash version
+func NodeStats(ctx context.Context, target *Target) (NodeStatsResponse, error) {
 	var response NodeStatsResponse
 
-	handler := &HTTPHandler{
-		Endpoint: endpoint + "/_node/stats",
+	handler := target.handler("node_stats", "/_node/stats")
+
+	if err := getMetrics(ctx, handler, &response); err != nil {
+		return response, err
 	}
 
-	err := getMetrics(handler, &response)
+	target.normalize(ctx, &response)
+
+	return response, nil
+}
+
+// LatestNodeStats returns the last snapshot of target's Poller if it has one,
+// and fetches node stats from target otherwise
+func LatestNodeStats(ctx context.Context, target *Target) (NodeStatsResponse, error) {
+	if target.Poller != nil {
+		stats, _, err := target.Poller.Snapshot()
+		return stats, err
+	}
 
-	return response, err
+	return NodeStats(ctx, target)
 }
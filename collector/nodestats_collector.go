@@ -1,669 +1,342 @@
 package collector
 
 import (
+	"context"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
+	"io"
 )
 
 // NodeStatsCollector type
 type NodeStatsCollector struct {
-	endpoint string
-
-	JvmThreadsCount     *prometheus.Desc
-	JvmThreadsPeakCount *prometheus.Desc
-
-	MemHeapUsedInBytes         *prometheus.Desc
-	MemHeapCommittedInBytes    *prometheus.Desc
-	MemHeapMaxInBytes          *prometheus.Desc
-	MemNonHeapUsedInBytes      *prometheus.Desc
-	MemNonHeapCommittedInBytes *prometheus.Desc
-
-	MemPoolPeakUsedInBytes  *prometheus.Desc
-	MemPoolUsedInBytes      *prometheus.Desc
-	MemPoolPeakMaxInBytes   *prometheus.Desc
-	MemPoolMaxInBytes       *prometheus.Desc
-	MemPoolCommittedInBytes *prometheus.Desc
-
-	GCCollectionTimeInMillis *prometheus.Desc
-	GCCollectionCount        *prometheus.Desc
-
-	ProcessOpenFileDescriptors    *prometheus.Desc
-	ProcessMaxFileDescriptors     *prometheus.Desc
-	ProcessMemTotalVirtualInBytes *prometheus.Desc
-	ProcessCPUTotalInMillis       *prometheus.Desc
-
-	PipelineDuration       *prometheus.Desc
-	PipelineEventsIn       *prometheus.Desc
-	PipelineEventsFiltered *prometheus.Desc
-	PipelineEventsOut      *prometheus.Desc
-
-	PipelinePluginEventsDuration *prometheus.Desc
-	PipelinePluginEventsIn       *prometheus.Desc
-	PipelinePluginEventsOut      *prometheus.Desc
-	PipelinePluginMatches        *prometheus.Desc
-	PipelinePluginFailures       *prometheus.Desc
-
-	PipelineQueueEvents          *prometheus.Desc
-	PipelineQueuePageCapacity    *prometheus.Desc
-	PipelineQueueMaxQueueSize    *prometheus.Desc
-	PipelineQueueMaxUnreadEvents *prometheus.Desc
-
-	PipelineDeadLetterQueueSizeInBytes *prometheus.Desc
-}
+	target *Target
 
-// NewNodeStatsCollector function
-func NewNodeStatsCollector(logstashEndpoint string) (Collector, error) {
-	const subsystem = "node"
+	metrics []specMetric // by index of nodeMetrics
 
-	return &NodeStatsCollector{
-		endpoint: logstashEndpoint,
-
-		JvmThreadsCount: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "jvm_threads_count"),
-			"jvm_threads_count",
-			nil,
-			nil,
-		),
-
-		JvmThreadsPeakCount: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "jvm_threads_peak_count"),
-			"jvm_threads_peak_count",
-			nil,
-			nil,
-		),
-
-		MemHeapUsedInBytes: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "mem_heap_used_bytes"),
-			"mem_heap_used_bytes",
-			nil,
-			nil,
-		),
-
-		MemHeapCommittedInBytes: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "mem_heap_committed_bytes"),
-			"mem_heap_committed_bytes",
-			nil,
-			nil,
-		),
-
-		MemHeapMaxInBytes: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "mem_heap_max_bytes"),
-			"mem_heap_max_bytes",
-			nil,
-			nil,
-		),
-
-		MemNonHeapUsedInBytes: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "mem_nonheap_used_bytes"),
-			"mem_nonheap_used_bytes",
-			nil,
-			nil,
-		),
-
-		MemNonHeapCommittedInBytes: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "mem_nonheap_committed_bytes"),
-			"mem_nonheap_committed_bytes",
-			nil,
-			nil,
-		),
-
-		MemPoolUsedInBytes: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "mem_pool_used_bytes"),
-			"mem_pool_used_bytes",
-			[]string{"pool"},
-			nil,
-		),
-
-		MemPoolPeakUsedInBytes: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "mem_pool_peak_used_bytes"),
-			"mem_pool_peak_used_bytes",
-			[]string{"pool"},
-			nil,
-		),
-
-		MemPoolPeakMaxInBytes: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "mem_pool_peak_max_bytes"),
-			"mem_pool_peak_max_bytes",
-			[]string{"pool"},
-			nil,
-		),
-
-		MemPoolMaxInBytes: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "mem_pool_max_bytes"),
-			"mem_pool_max_bytes",
-			[]string{"pool"},
-			nil,
-		),
-
-		MemPoolCommittedInBytes: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "mem_pool_committed_bytes"),
-			"mem_pool_committed_bytes",
-			[]string{"pool"},
-			nil,
-		),
-
-		GCCollectionTimeInMillis: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "gc_collection_duration_seconds_total"),
-			"gc_collection_duration_seconds_total",
-			[]string{"collector"},
-			nil,
-		),
-
-		GCCollectionCount: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "gc_collection_total"),
-			"gc_collection_total",
-			[]string{"collector"},
-			nil,
-		),
-
-		ProcessOpenFileDescriptors: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "process_open_filedescriptors"),
-			"process_open_filedescriptors",
-			nil,
-			nil,
-		),
-
-		ProcessMaxFileDescriptors: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "process_max_filedescriptors"),
-			"process_max_filedescriptors",
-			nil,
-			nil,
-		),
-
-		ProcessMemTotalVirtualInBytes: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "process_mem_total_virtual_bytes"),
-			"process_mem_total_virtual_bytes",
-			nil,
-			nil,
-		),
-
-		ProcessCPUTotalInMillis: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "process_cpu_total_seconds_total"),
-			"process_cpu_total_seconds_total",
-			nil,
-			nil,
-		),
-
-		PipelineDuration: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "pipeline_duration_seconds_total"),
-			"pipeline_duration_seconds_total",
-			[]string{"pipeline"},
-			nil,
-		),
-
-		PipelineEventsIn: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "pipeline_events_in_total"),
-			"pipeline_events_in_total",
-			[]string{"pipeline"},
-			nil,
-		),
-
-		PipelineEventsFiltered: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "pipeline_events_filtered_total"),
-			"pipeline_events_filtered_total",
-			[]string{"pipeline"},
-			nil,
-		),
-
-		PipelineEventsOut: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "pipeline_events_out_total"),
-			"pipeline_events_out_total",
-			[]string{"pipeline"},
-			nil,
-		),
-
-		PipelinePluginEventsDuration: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "plugin_duration_seconds_total"),
-			"plugin_duration_seconds",
-			[]string{"pipeline", "plugin", "plugin_id", "plugin_type"},
-			nil,
-		),
-
-		PipelinePluginEventsIn: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "plugin_events_in_total"),
-			"plugin_events_in",
-			[]string{"pipeline", "plugin", "plugin_id", "plugin_type"},
-			nil,
-		),
-
-		PipelinePluginEventsOut: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "plugin_events_out_total"),
-			"plugin_events_out",
-			[]string{"pipeline", "plugin", "plugin_id", "plugin_type"},
-			nil,
-		),
-
-		PipelinePluginMatches: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "plugin_matches_total"),
-			"plugin_matches",
-			[]string{"pipeline", "plugin", "plugin_id", "plugin_type"},
-			nil,
-		),
-
-		PipelinePluginFailures: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "plugin_failures_total"),
-			"plugin_failures",
-			[]string{"pipeline", "plugin", "plugin_id", "plugin_type"},
-			nil,
-		),
-
-		PipelineQueueEvents: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "queue_events"),
-			"queue_events",
-			[]string{"pipeline"},
-			nil,
-		),
-
-		PipelineQueuePageCapacity: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "queue_page_capacity_bytes"),
-			"queue_page_capacity_bytes",
-			[]string{"pipeline"},
-			nil,
-		),
-
-		PipelineQueueMaxQueueSize: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "queue_max_size_bytes"),
-			"queue_max_size_bytes",
-			[]string{"pipeline"},
-			nil,
-		),
-
-		PipelineQueueMaxUnreadEvents: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "queue_max_unread_events"),
-			"queue_max_unread_events",
-			[]string{"pipeline"},
-			nil,
-		),
-
-		PipelineDeadLetterQueueSizeInBytes: prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, subsystem, "dead_letter_queue_size_bytes"),
-			"dead_letter_queue_size_bytes",
-			[]string{"pipeline"},
-			nil,
-		),
-	}, nil
-}
+	SchemaAdapter               *prometheus.Desc
+	PipelineReloadsLastError    *prometheus.Desc
+	PipelineDeadLetterQueueInfo *prometheus.Desc
+	PipelinePluginMetric        *prometheus.Desc
 
-// Collect function implements nodestats_collector collector
-func (c *NodeStatsCollector) Collect(ch chan<- prometheus.Metric) error {
-	if desc, err := c.collect(ch); err != nil {
-		log.Error("Failed collecting node metrics", desc, err)
-		return err
-	}
-	return nil
+	NodeFlow     map[string]*prometheus.Desc
+	PipelineFlow map[string]*prometheus.Desc
+	PluginFlow   map[string]*prometheus.Desc
+
+	PluginMetrics []*prometheus.Desc // by index of wellKnownPluginMetrics
 }
 
-func (c *NodeStatsCollector) collect(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
-	stats, err := NodeStats(c.endpoint)
-	if err != nil {
-		return nil, err
+var (
+	nodeFlowMetrics = map[string]string{
+		"input_throughput":   "Events per second received by all inputs.",
+		"filter_throughput":  "Events per second processed by all filters.",
+		"output_throughput":  "Events per second sent by all outputs.",
+		"queue_backpressure": "Average number of inputs blocked pushing events into the queue.",
+		"worker_concurrency": "Average number of workers processing events concurrently.",
 	}
 
-	ch <- prometheus.MustNewConstMetric(
-		c.JvmThreadsCount,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Threads.Count),
-	)
-
-	ch <- prometheus.MustNewConstMetric(
-		c.JvmThreadsPeakCount,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Threads.PeakCount),
-	)
-
-	ch <- prometheus.MustNewConstMetric(
-		c.MemHeapUsedInBytes,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Mem.HeapUsedInBytes),
-	)
-
-	ch <- prometheus.MustNewConstMetric(
-		c.MemHeapMaxInBytes,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Mem.HeapMaxInBytes),
-	)
+	pipelineFlowMetrics = map[string]string{
+		"input_throughput":              "Events per second received by the pipeline's inputs.",
+		"filter_throughput":             "Events per second processed by the pipeline's filters.",
+		"output_throughput":             "Events per second sent by the pipeline's outputs.",
+		"queue_backpressure":            "Average number of the pipeline's inputs blocked pushing events into the queue.",
+		"worker_concurrency":            "Average number of the pipeline's workers processing events concurrently.",
+		"worker_utilization":            "Percentage of the pipeline's worker capacity in use.",
+		"queue_persisted_growth_bytes":  "Growth of the pipeline's persisted queue in bytes per second.",
+		"queue_persisted_growth_events": "Growth of the pipeline's persisted queue in events per second.",
+	}
 
-	ch <- prometheus.MustNewConstMetric(
-		c.MemHeapCommittedInBytes,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Mem.HeapCommittedInBytes),
-	)
+	pluginFlowMetrics = map[string]string{
+		"throughput":              "Events per second received by an input plugin.",
+		"worker_utilization":      "Percentage of the pipeline's worker capacity spent in a filter or output plugin.",
+		"worker_millis_per_event": "Milliseconds of worker time a filter or output plugin spends per event.",
+	}
+)
 
-	ch <- prometheus.MustNewConstMetric(
-		c.MemNonHeapUsedInBytes,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Mem.NonHeapUsedInBytes),
-	)
+// flowDef describes the flow metric name of the objects of scope, labeled by
+// window
+func flowDef(prefix, name, help string, scope *metricScope) metricDef {
+	source := "flow." + name + ".<window>"
+	if scope.path != "" {
+		source = scope.path + "." + source
+	}
+	return metricDef{
+		name:      prefix + name,
+		help:      help,
+		valueType: prometheus.GaugeValue,
+		labels:    append(append([]string{}, scope.labels...), "window"),
+		source:    source,
+	}
+}
 
-	ch <- prometheus.MustNewConstMetric(
-		c.MemNonHeapCommittedInBytes,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Mem.NonHeapCommittedInBytes),
-	)
+// newFlowDescs returns a desc for each flow metric of the objects of scope
+func newFlowDescs(subsystem, prefix string, metrics map[string]string, scope *metricScope) map[string]*prometheus.Desc {
+	descs := make(map[string]*prometheus.Desc, len(metrics))
+	for name, help := range metrics {
+		descs[name] = flowDef(prefix, name, help, scope).desc(subsystem)
+	}
+	return descs
+}
 
-	ch <- prometheus.MustNewConstMetric(
-		c.MemPoolPeakUsedInBytes,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Mem.Pools.Old.PeakUsedInBytes),
-		"old",
-	)
+// NewNodeStatsCollector function
+func NewNodeStatsCollector(target *Target) (Collector, error) {
+	const subsystem = "node"
 
-	ch <- prometheus.MustNewConstMetric(
-		c.MemPoolUsedInBytes,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Mem.Pools.Old.UsedInBytes),
-		"old",
-	)
+	metrics, err := newSpecMetrics(subsystem, nodeMetrics)
+	if err != nil {
+		return nil, err
+	}
 
-	ch <- prometheus.MustNewConstMetric(
-		c.MemPoolPeakMaxInBytes,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Mem.Pools.Old.PeakMaxInBytes),
-		"old",
-	)
+	return &NodeStatsCollector{
+		target:  target,
+		metrics: metrics,
 
-	ch <- prometheus.MustNewConstMetric(
-		c.MemPoolMaxInBytes,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Mem.Pools.Old.MaxInBytes),
-		"old",
-	)
+		SchemaAdapter:               schemaAdapterInfo.desc(subsystem),
+		PipelineReloadsLastError:    pipelineReloadsLastErrorInfo.desc(subsystem),
+		PipelineDeadLetterQueueInfo: deadLetterQueueInfo.desc(subsystem),
+		PipelinePluginMetric:        pluginMetric.desc(subsystem),
 
-	ch <- prometheus.MustNewConstMetric(
-		c.MemPoolCommittedInBytes,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Mem.Pools.Old.CommittedInBytes),
-		"old",
-	)
+		NodeFlow:     newFlowDescs(subsystem, "flow_", nodeFlowMetrics, nodeScope),
+		PipelineFlow: newFlowDescs(subsystem, "pipeline_flow_", pipelineFlowMetrics, pipelineScope),
+		PluginFlow:   newFlowDescs(subsystem, "plugin_flow_", pluginFlowMetrics, pluginScope),
 
-	ch <- prometheus.MustNewConstMetric(
-		c.MemPoolPeakUsedInBytes,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Mem.Pools.Old.PeakUsedInBytes),
-		"young",
-	)
+		PluginMetrics: newPluginMetricDescs(subsystem, wellKnownPluginMetrics),
+	}, nil
+}
 
-	ch <- prometheus.MustNewConstMetric(
-		c.MemPoolUsedInBytes,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Mem.Pools.Young.UsedInBytes),
-		"young",
-	)
+// WriteNodeMetricReference writes a Markdown table of the metrics of the node
+// collector
+func WriteNodeMetricReference(w io.Writer) error {
+	defs := []metricDef{schemaAdapterInfo, pipelineReloadsLastErrorInfo, deadLetterQueueInfo, pluginMetric}
+	for _, spec := range nodeMetrics {
+		defs = append(defs, spec.def())
+	}
+	for _, flows := range []struct {
+		prefix  string
+		metrics map[string]string
+		scope   *metricScope
+	}{
+		{"flow_", nodeFlowMetrics, nodeScope},
+		{"pipeline_flow_", pipelineFlowMetrics, pipelineScope},
+		{"plugin_flow_", pluginFlowMetrics, pluginScope},
+	} {
+		for name, help := range flows.metrics {
+			defs = append(defs, flowDef(flows.prefix, name, help, flows.scope))
+		}
+	}
+	for _, spec := range wellKnownPluginMetrics {
+		defs = append(defs, spec.def())
+	}
 
-	ch <- prometheus.MustNewConstMetric(
-		c.MemPoolPeakMaxInBytes,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Mem.Pools.Old.PeakMaxInBytes),
-		"young",
-	)
+	return writeMetricReference(w, "node", defs)
+}
 
-	ch <- prometheus.MustNewConstMetric(
-		c.MemPoolMaxInBytes,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Mem.Pools.Young.MaxInBytes),
-		"young",
-	)
+// Describe sends the descs of all metrics of the collector
+func (c *NodeStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range c.metrics {
+		ch <- m.desc
+	}
 
-	ch <- prometheus.MustNewConstMetric(
-		c.MemPoolCommittedInBytes,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Mem.Pools.Young.CommittedInBytes),
-		"young",
-	)
+	ch <- c.SchemaAdapter
+	ch <- c.PipelineReloadsLastError
+	ch <- c.PipelineDeadLetterQueueInfo
+	ch <- c.PipelinePluginMetric
 
-	ch <- prometheus.MustNewConstMetric(
-		c.MemPoolPeakUsedInBytes,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Mem.Pools.Old.PeakUsedInBytes),
-		"survivor",
-	)
+	for _, descs := range []map[string]*prometheus.Desc{c.NodeFlow, c.PipelineFlow, c.PluginFlow} {
+		for _, desc := range descs {
+			ch <- desc
+		}
+	}
 
-	ch <- prometheus.MustNewConstMetric(
-		c.MemPoolUsedInBytes,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Mem.Pools.Survivor.UsedInBytes),
-		"survivor",
-	)
+	for _, desc := range c.PluginMetrics {
+		ch <- desc
+	}
+}
 
-	ch <- prometheus.MustNewConstMetric(
-		c.MemPoolPeakMaxInBytes,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Mem.Pools.Old.PeakMaxInBytes),
-		"survivor",
-	)
+// collectPluginMetrics sends the well-known stats of plugin as typed metrics,
+// and its other plugin-specific stats selected by the target's
+// PluginMetricKeys as logstash_node_plugin_metric
+func (c *NodeStatsCollector) collectPluginMetrics(ch chan<- prometheus.Metric, plugin Plugin, pipelineID, pluginType string) {
+	for key, value := range pluginStats(plugin) {
+		typed := false
+		for i, spec := range wellKnownPluginMetrics {
+			labels, ok := spec.match(pluginType, plugin.Name, key)
+			if !ok {
+				continue
+			}
+			typed = true
 
-	ch <- prometheus.MustNewConstMetric(
-		c.MemPoolMaxInBytes,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Mem.Pools.Survivor.MaxInBytes),
-		"survivor",
-	)
+			ch <- prometheus.MustNewConstMetric(
+				c.PluginMetrics[i],
+				spec.valueType,
+				value,
+				append([]string{pipelineID, plugin.Name, plugin.ID, pluginType}, labels...)...,
+			)
+		}
 
-	ch <- prometheus.MustNewConstMetric(
-		c.MemPoolCommittedInBytes,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Mem.Pools.Survivor.CommittedInBytes),
-		"survivor",
-	)
+		if _, ok := plugin.Metrics[key]; !ok || typed || !c.target.PluginMetricKeys.Match(key) {
+			continue
+		}
 
-	ch <- prometheus.MustNewConstMetric(
-		c.GCCollectionTimeInMillis,
-		prometheus.CounterValue,
-		float64(stats.Jvm.Gc.Collectors.Old.CollectionTimeInMillis),
-		"old",
-	)
+		ch <- prometheus.MustNewConstMetric(
+			c.PipelinePluginMetric,
+			prometheus.UntypedValue,
+			value,
+			pipelineID,
+			plugin.Name,
+			plugin.ID,
+			pluginType,
+			key,
+		)
+	}
+}
 
-	ch <- prometheus.MustNewConstMetric(
-		c.GCCollectionCount,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Gc.Collectors.Old.CollectionCount),
-		"old",
-	)
+// collectFlow sends a gauge per known flow metric and window
+func collectFlow(ch chan<- prometheus.Metric, descs map[string]*prometheus.Desc, flow FlowMetrics, labels ...string) {
+	for name, windows := range flow {
+		desc, ok := descs[name]
+		if !ok {
+			continue
+		}
 
-	ch <- prometheus.MustNewConstMetric(
-		c.GCCollectionTimeInMillis,
-		prometheus.CounterValue,
-		float64(stats.Jvm.Gc.Collectors.Young.CollectionTimeInMillis),
-		"young",
-	)
+		for window, value := range windows {
+			ch <- prometheus.MustNewConstMetric(
+				desc,
+				prometheus.GaugeValue,
+				value,
+				append(labels, window)...,
+			)
+		}
+	}
+}
 
-	ch <- prometheus.MustNewConstMetric(
-		c.GCCollectionCount,
-		prometheus.GaugeValue,
-		float64(stats.Jvm.Gc.Collectors.Young.CollectionCount),
-		"young",
-	)
+// Collect function implements nodestats_collector collector
+func (c *NodeStatsCollector) Collect(ctx context.Context, ch chan<- prometheus.Metric) error {
+	if desc, err := c.collect(ctx, ch); err != nil {
+		log.Error("Failed collecting node metrics", desc, err)
+		return err
+	}
+	return nil
+}
 
-	ch <- prometheus.MustNewConstMetric(
-		c.ProcessOpenFileDescriptors,
-		prometheus.GaugeValue,
-		float64(stats.Process.OpenFileDescriptors),
-	)
+func (c *NodeStatsCollector) collect(ctx context.Context, ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
+	stats, err := LatestNodeStats(ctx, c.target)
+	if err != nil {
+		return nil, err
+	}
 
-	ch <- prometheus.MustNewConstMetric(
-		c.ProcessMaxFileDescriptors,
-		prometheus.GaugeValue,
-		float64(stats.Process.MaxFileDescriptors),
-	)
+	objects := c.scopedValues(ctx, stats)
+	for _, m := range c.metrics {
+		m.collect(ch, objects[m.scope])
+	}
 
 	ch <- prometheus.MustNewConstMetric(
-		c.ProcessMemTotalVirtualInBytes,
+		c.SchemaAdapter,
 		prometheus.GaugeValue,
-		float64(stats.Process.Mem.TotalVirtualInBytes),
+		1,
+		stats.Schema,
+		stats.SchemaVersion,
 	)
 
-	ch <- prometheus.MustNewConstMetric(
-		c.ProcessCPUTotalInMillis,
-		prometheus.CounterValue,
-		float64(stats.Process.CPU.TotalInMillis/1000),
-	)
-
-	// For backwards compatibility with Logstash 5
-	pipelines := make(map[string]Pipeline)
-	if len(stats.Pipelines) == 0 {
-		pipelines["main"] = stats.Pipeline
-	} else {
-		pipelines = stats.Pipelines
-	}
-
-	for pipelineID, pipeline := range pipelines {
-		ch <- prometheus.MustNewConstMetric(
-			c.PipelineDuration,
-			prometheus.CounterValue,
-			float64(pipeline.Events.DurationInMillis/1000),
-			pipelineID,
-		)
-
-		ch <- prometheus.MustNewConstMetric(
-			c.PipelineEventsIn,
-			prometheus.CounterValue,
-			float64(pipeline.Events.In),
-			pipelineID,
-		)
+	collectFlow(ch, c.NodeFlow, stats.Flow)
 
-		ch <- prometheus.MustNewConstMetric(
-			c.PipelineEventsFiltered,
-			prometheus.CounterValue,
-			float64(pipeline.Events.Filtered),
-			pipelineID,
-		)
-
-		ch <- prometheus.MustNewConstMetric(
-			c.PipelineEventsOut,
-			prometheus.CounterValue,
-			float64(pipeline.Events.Out),
-			pipelineID,
-		)
+	for pipelineID, pipeline := range stats.Pipelines {
+		collectFlow(ch, c.PipelineFlow, pipeline.Flow, pipelineID)
 
-		for _, plugin := range pipeline.Plugins.Inputs {
+		if lastError := pipeline.Reloads.LastError; lastError != nil {
 			ch <- prometheus.MustNewConstMetric(
-				c.PipelinePluginEventsIn,
-				prometheus.CounterValue,
-				float64(plugin.Events.In),
-				pipelineID,
-				plugin.Name,
-				plugin.ID,
-				"input",
-			)
-			ch <- prometheus.MustNewConstMetric(
-				c.PipelinePluginEventsOut,
-				prometheus.CounterValue,
-				float64(plugin.Events.Out),
+				c.PipelineReloadsLastError,
+				prometheus.GaugeValue,
+				float64(1),
 				pipelineID,
-				plugin.Name,
-				plugin.ID,
-				"input",
+				truncate(lastError.Message, maxErrorLabelLength),
+				backtraceClass(lastError.Backtrace),
 			)
 		}
 
-		for _, plugin := range pipeline.Plugins.Filters {
-			ch <- prometheus.MustNewConstMetric(
-				c.PipelinePluginEventsDuration,
-				prometheus.CounterValue,
-				float64(plugin.Events.DurationInMillis/1000),
-				pipelineID,
-				plugin.Name,
-				plugin.ID,
-				"filter",
-			)
-			ch <- prometheus.MustNewConstMetric(
-				c.PipelinePluginEventsIn,
-				prometheus.CounterValue,
-				float64(plugin.Events.In),
-				pipelineID,
-				plugin.Name,
-				plugin.ID,
-				"filter",
-			)
-			ch <- prometheus.MustNewConstMetric(
-				c.PipelinePluginEventsOut,
-				prometheus.CounterValue,
-				float64(plugin.Events.Out),
-				pipelineID,
-				plugin.Name,
-				plugin.ID,
-				"filter",
-			)
-			ch <- prometheus.MustNewConstMetric(
-				c.PipelinePluginMatches,
-				prometheus.CounterValue,
-				float64(plugin.Matches),
-				pipelineID,
-				plugin.Name,
-				plugin.ID,
-				"filter",
-			)
-			ch <- prometheus.MustNewConstMetric(
-				c.PipelinePluginFailures,
-				prometheus.CounterValue,
-				float64(plugin.Failures),
-				pipelineID,
-				plugin.Name,
-				plugin.ID,
-				"filter",
-			)
+		for pluginType, plugins := range pipelinePlugins(pipeline) {
+			for _, plugin := range plugins {
+				collectFlow(ch, c.PluginFlow, plugin.Flow, pipelineID, plugin.Name, plugin.ID, pluginType)
+				c.collectPluginMetrics(ch, plugin, pipelineID, pluginType)
+			}
 		}
 
-		for _, plugin := range pipeline.Plugins.Outputs {
-			ch <- prometheus.MustNewConstMetric(
-				c.PipelinePluginEventsIn,
-				prometheus.CounterValue,
-				float64(plugin.Events.In),
-				pipelineID,
-				plugin.Name,
-				plugin.ID,
-				"output",
-			)
+		if dlq := pipeline.DeadLetterQueue; dlq != nil && (dlq.StoragePolicy != nil || dlq.LastError != nil) {
+			storagePolicy, lastError := "", ""
+			if dlq.StoragePolicy != nil {
+				storagePolicy = *dlq.StoragePolicy
+			}
+			if dlq.LastError != nil {
+				lastError = truncate(*dlq.LastError, maxErrorLabelLength)
+			}
 			ch <- prometheus.MustNewConstMetric(
-				c.PipelinePluginEventsOut,
-				prometheus.CounterValue,
-				float64(plugin.Events.Out),
+				c.PipelineDeadLetterQueueInfo,
+				prometheus.GaugeValue,
+				float64(1),
 				pipelineID,
-				plugin.Name,
-				plugin.ID,
-				"output",
+				storagePolicy,
+				lastError,
 			)
 		}
+	}
 
-		if pipeline.Queue.Type != "memory" {
-			ch <- prometheus.MustNewConstMetric(
-				c.PipelineQueueEvents,
-				prometheus.CounterValue,
-				float64(pipeline.Queue.Events),
-				pipelineID,
-			)
+	return nil, nil
+}
 
-			ch <- prometheus.MustNewConstMetric(
-				c.PipelineQueuePageCapacity,
-				prometheus.CounterValue,
-				float64(pipeline.Queue.Capacity.PageCapacityInBytes),
-				pipelineID,
-			)
+// scopedValues returns the objects of each scope in stats, labeled
+func (c *NodeStatsCollector) scopedValues(ctx context.Context, stats NodeStatsResponse) map[*metricScope][]scopedValue {
+	objects := map[*metricScope][]scopedValue{
+		nodeScope: {{value: stats}},
+	}
 
-			ch <- prometheus.MustNewConstMetric(
-				c.PipelineQueueMaxQueueSize,
-				prometheus.CounterValue,
-				float64(pipeline.Queue.Capacity.MaxQueueSizeInBytes),
-				pipelineID,
-			)
+	for name, pool := range stats.Jvm.Mem.Pools {
+		objects[memPoolScope] = append(objects[memPoolScope], scopedValue{[]string{name}, pool})
+	}
 
-			ch <- prometheus.MustNewConstMetric(
-				c.PipelineQueueMaxUnreadEvents,
-				prometheus.CounterValue,
-				float64(pipeline.Queue.Capacity.MaxUnreadEvents),
-				pipelineID,
-			)
+	gcNames := c.target.gcCollectorNames(ctx, stats.Jvm.UptimeInMillis)
+	for name, gc := range stats.Jvm.Gc.Collectors {
+		jvmName, ok := gcNames[name]
+		if !ok {
+			jvmName = name
 		}
+		objects[gcScope] = append(objects[gcScope], scopedValue{[]string{name, jvmName}, gc})
+	}
 
-		if pipeline.DeadLetterQueue.QueueSizeInBytes != 0 {
-			ch <- prometheus.MustNewConstMetric(
-				c.PipelineDeadLetterQueueSizeInBytes,
-				prometheus.GaugeValue,
-				float64(pipeline.DeadLetterQueue.QueueSizeInBytes),
-				pipelineID,
-			)
+	if cgroup := stats.Os.Cgroup; cgroup != nil {
+		objects[cgroupCPUAcctScope] = []scopedValue{{[]string{cgroup.CPUAcct.ControlGroup}, cgroup.CPUAcct}}
+		objects[cgroupCPUScope] = []scopedValue{{[]string{cgroup.CPU.ControlGroup}, cgroup.CPU}}
+	}
+
+	for pipelineID, pipeline := range stats.Pipelines {
+		objects[pipelineScope] = append(objects[pipelineScope], scopedValue{[]string{pipelineID}, pipeline})
+
+		for pluginType, plugins := range pipelinePlugins(pipeline) {
+			for _, plugin := range plugins {
+				labels := []string{pipelineID, plugin.Name, plugin.ID, pluginType}
+				objects[pluginScope] = append(objects[pluginScope], scopedValue{labels, plugin})
+			}
+		}
+
+		if queue := pipeline.Queue; queue.Type == "persisted" {
+			objects[queueScope] = append(objects[queueScope], scopedValue{[]string{pipelineID}, queue})
+			if data := queue.Data; data.Path != "" {
+				labels := []string{pipelineID, data.Path, data.StorageType}
+				objects[queueDataScope] = append(objects[queueDataScope], scopedValue{labels, data})
+			}
+		}
+
+		if dlq := pipeline.DeadLetterQueue; dlq != nil {
+			objects[deadLetterQueueScope] = append(objects[deadLetterQueueScope], scopedValue{[]string{pipelineID}, dlq})
 		}
 	}
 
-	return nil, nil
+	return objects
+}
+
+// pipelinePlugins returns the plugins of pipeline by type
+func pipelinePlugins(pipeline Pipeline) map[string][]Plugin {
+	return map[string][]Plugin{
+		"input":  pipeline.Plugins.Inputs,
+		"filter": pipeline.Plugins.Filters,
+		"output": pipeline.Plugins.Outputs,
+	}
 }
@@ -2,6 +2,7 @@ package collector
 
 import (
 	"bytes"
+	"context"
 	"io/ioutil"
 	"net/http"
 	"testing"
@@ -263,12 +264,19 @@ var dlQueueJSON = []byte(`
 
 type MockHTTPHandler struct {
 	ReturnJSON []byte
+	StatusCode int
 	Endpoint   string
 }
 
-func (m *MockHTTPHandler) Get() (http.Response, error) {
+func (m *MockHTTPHandler) Get(ctx context.Context) (http.Response, error) {
+	statusCode := m.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
 	response := &http.Response{
-		Body: ioutil.NopCloser(bytes.NewReader(m.ReturnJSON)),
+		StatusCode: statusCode,
+		Body:       ioutil.NopCloser(bytes.NewReader(m.ReturnJSON)),
 	}
 
 	return *response, nil
@@ -278,7 +286,7 @@ func TestPipelineNoQueueStats(t *testing.T) {
 	var response NodeStatsResponse
 
 	m := &MockHTTPHandler{ReturnJSON: noQueueJSON}
-	getMetrics(m, &response)
+	getMetrics(context.Background(), m, &response)
 
 	if response.Pipeline.Queue.Capacity.MaxUnreadEvents == 12 {
 		t.Fail()
@@ -289,7 +297,7 @@ func TestPipelineQueueStats(t *testing.T) {
 	var response NodeStatsResponse
 
 	m := &MockHTTPHandler{ReturnJSON: queueJSON}
-	getMetrics(m, &response)
+	getMetrics(context.Background(), m, &response)
 
 	if response.Pipeline.Queue.Capacity.MaxUnreadEvents != 12 {
 		t.Fail()
@@ -300,9 +308,572 @@ func TestPipelineDLQueueStats(t *testing.T) {
 	var response NodeStatsResponse
 
 	m := &MockHTTPHandler{ReturnJSON: dlQueueJSON}
-	getMetrics(m, &response)
+	getMetrics(context.Background(), m, &response)
 
 	if response.Pipeline.DeadLetterQueue.QueueSizeInBytes != 1337 {
 		t.Fail()
 	}
 }
+
+func TestGetMetricsStatusCodeError(t *testing.T) {
+	var response NodeStatsResponse
+
+	m := &MockHTTPHandler{ReturnJSON: queueJSON, StatusCode: http.StatusServiceUnavailable}
+	if err := getMetrics(context.Background(), m, &response); err == nil {
+		t.Error("expected an error for a non-2xx status code")
+	}
+}
+
+func TestGetMetricsDecodeError(t *testing.T) {
+	var response NodeStatsResponse
+
+	m := &MockHTTPHandler{ReturnJSON: []byte(`{"pipeline": `)}
+	if err := getMetrics(context.Background(), m, &response); err == nil {
+		t.Error("expected an error for malformed json")
+	}
+}
+
+var flowJSON = []byte(`
+{
+  "version": "8.5.0",
+  "flow": {
+    "input_throughput": {"current": 12.5, "lifetime": 10.1},
+    "queue_backpressure": {"current": 0.5, "last_1_minute": 0.25}
+  },
+  "pipelines": {
+    "main": {
+      "flow": {
+        "worker_concurrency": {"current": 1.5, "last_5_minutes": 1.25},
+        "queue_persisted_growth_events": {"current": 0.0, "lifetime": -1.5},
+        "not_yet_known": {"current": 1}
+      },
+      "plugins": {
+        "inputs": [{
+          "id": "beats-in",
+          "name": "beats",
+          "flow": {"throughput": {"current": 12.5}}
+        }],
+        "filters": [{
+          "id": "grok-1",
+          "name": "grok",
+          "flow": {
+            "worker_utilization": {"last_1_hour": 33.3},
+            "worker_millis_per_event": {"lifetime": 0.75}
+          }
+        }],
+        "outputs": []
+      }
+    }
+  }
+}
+`)
+
+func TestPipelineFlowStats(t *testing.T) {
+	runCollectorTests(t, NewNodeStatsCollector, []collectorTest{
+		{
+			name:      "flow",
+			responses: map[string][]byte{"/_node/stats": flowJSON},
+			expected: map[string]float64{
+				`logstash_node_flow_input_throughput{window="current"}`:                                                                                      12.5,
+				`logstash_node_flow_input_throughput{window="lifetime"}`:                                                                                     10.1,
+				`logstash_node_flow_queue_backpressure{window="current"}`:                                                                                    0.5,
+				`logstash_node_flow_queue_backpressure{window="last_1_minute"}`:                                                                              0.25,
+				`logstash_node_pipeline_flow_worker_concurrency{pipeline="main",window="current"}`:                                                           1.5,
+				`logstash_node_pipeline_flow_worker_concurrency{pipeline="main",window="last_5_minutes"}`:                                                    1.25,
+				`logstash_node_pipeline_flow_queue_persisted_growth_events{pipeline="main",window="current"}`:                                                0,
+				`logstash_node_pipeline_flow_queue_persisted_growth_events{pipeline="main",window="lifetime"}`:                                               -1.5,
+				`logstash_node_plugin_flow_throughput{pipeline="main",plugin="beats",plugin_id="beats-in",plugin_type="input",window="current"}`:             12.5,
+				`logstash_node_plugin_flow_worker_utilization{pipeline="main",plugin="grok",plugin_id="grok-1",plugin_type="filter",window="last_1_hour"}`:   33.3,
+				`logstash_node_plugin_flow_worker_millis_per_event{pipeline="main",plugin="grok",plugin_id="grok-1",plugin_type="filter",window="lifetime"}`: 0.75,
+			},
+			absent: []string{"logstash_node_pipeline_flow_not_yet_known"},
+		},
+	})
+}
+
+var reloadsJSON = []byte(`
+{
+  "version": "7.17.0",
+  "reloads": {"successes": 3, "failures": 1},
+  "pipelines": {
+    "main": {
+      "reloads": {
+        "successes": 2,
+        "failures": 1,
+        "last_success_timestamp": "2022-03-01T10:00:00.000Z",
+        "last_failure_timestamp": "2022-03-01T10:05:30.500Z",
+        "last_error": {
+          "message": "Expected one of [ \\t\\r\\n], \"#\", \"{\" at line 3, column 9 (byte 25) after input",
+          "backtrace": [
+            "/usr/share/logstash/logstash-core/lib/logstash/compiler.rb:32:in ` + "`" + `compile_imperative'",
+            "org.logstash.execution.AbstractPipelineExt.initialize(AbstractPipelineExt.java:183)"
+          ]
+        }
+      }
+    },
+    "other": {
+      "reloads": {
+        "successes": 0,
+        "failures": 0,
+        "last_success_timestamp": null,
+        "last_failure_timestamp": null,
+        "last_error": null
+      }
+    }
+  }
+}
+`)
+
+func TestReloadStats(t *testing.T) {
+	runCollectorTests(t, NewNodeStatsCollector, []collectorTest{
+		{
+			name:      "reloads",
+			responses: map[string][]byte{"/_node/stats": reloadsJSON},
+			expected: map[string]float64{
+				`logstash_node_reloads_successes_total`:                                          3,
+				`logstash_node_reloads_failures_total`:                                           1,
+				`logstash_node_pipeline_reloads_successes_total{pipeline="main"}`:                2,
+				`logstash_node_pipeline_reloads_failures_total{pipeline="main"}`:                 1,
+				`logstash_node_pipeline_reloads_last_success_timestamp_seconds{pipeline="main"}`: 1646128800,
+				`logstash_node_pipeline_reloads_last_failure_timestamp_seconds{pipeline="main"}`: 1646129130.5,
+				`logstash_node_pipeline_reloads_successes_total{pipeline="other"}`:               0,
+				`logstash_node_pipeline_reloads_failures_total{pipeline="other"}`:                0,
+				`logstash_node_pipeline_reloads_last_error_info{backtrace_class="logstash/compiler",message="Expected one of [ \\t\\r\\n], \"#\", \"{\" at line 3, column 9 (byte 25) after input",pipeline="main"}`: 1,
+			},
+		},
+	})
+}
+
+func TestBacktraceClass(t *testing.T) {
+	for frame, expected := range map[string]string{
+		"/usr/share/logstash/logstash-core/lib/logstash/pipeline_action/reload.rb:40:in `execute'": "logstash/pipeline_action/reload",
+		"org.logstash.config.ir.ConfigCompiler.compileSources(ConfigCompiler.java:50)":             "org.logstash.config.ir.ConfigCompiler",
+		"something else": "",
+	} {
+		if actual := backtraceClass([]string{frame}); actual != expected {
+			t.Errorf("backtraceClass(%q) = %q, expected %q", frame, actual, expected)
+		}
+	}
+}
+
+var queueV6JSON = []byte(`
+{
+  "version": "6.8.0",
+  "pipelines": {
+    "main": {
+      "queue": {
+        "type": "persisted",
+        "events": 42,
+        "capacity": {
+          "queue_size_in_bytes": 4096,
+          "page_capacity_in_bytes": 67108864,
+          "max_queue_size_in_bytes": 1073741824,
+          "max_unread_events": 0
+        },
+        "data": {
+          "path": "/var/lib/logstash/queue/main",
+          "free_space_in_bytes": 936886480896,
+          "storage_type": "ext4"
+        }
+      }
+    }
+  }
+}
+`)
+
+var queueV7JSON = []byte(`
+{
+  "version": "7.17.0",
+  "queue": {"events_count": 42},
+  "pipelines": {
+    "main": {
+      "queue": {
+        "type": "persisted",
+        "capacity": {
+          "max_unread_events": 0,
+          "page_capacity_in_bytes": 67108864,
+          "max_queue_size_in_bytes": 1073741824,
+          "queue_size_in_bytes": 4096
+        },
+        "data": {
+          "path": "/var/lib/logstash/queue/main",
+          "free_space_in_bytes": 936886480896,
+          "storage_type": "ext4"
+        },
+        "events": 42,
+        "events_count": 42,
+        "queue_size_in_bytes": 4096,
+        "max_queue_size_in_bytes": 1073741824
+      }
+    },
+    "memory": {
+      "queue": {
+        "type": "memory",
+        "events_count": 0,
+        "queue_size_in_bytes": 0,
+        "max_queue_size_in_bytes": 0
+      }
+    }
+  }
+}
+`)
+
+func TestPersistedQueueStats(t *testing.T) {
+	expected := map[string]float64{
+		`logstash_node_queue_events{pipeline="main"}`:                                                                   42,
+		`logstash_node_queue_size_bytes{pipeline="main"}`:                                                               4096,
+		`logstash_node_queue_max_size_bytes{pipeline="main"}`:                                                           1073741824,
+		`logstash_node_queue_max_unread_events{pipeline="main"}`:                                                        0,
+		`logstash_node_queue_page_capacity_bytes{pipeline="main"}`:                                                      67108864,
+		`logstash_node_queue_free_space_bytes{path="/var/lib/logstash/queue/main",pipeline="main",storage_type="ext4"}`: 936886480896,
+	}
+
+	runCollectorTests(t, NewNodeStatsCollector, []collectorTest{
+		{
+			name:      "persisted queue 6.x",
+			responses: map[string][]byte{"/_node/stats": queueV6JSON},
+			expected:  expected,
+		},
+		{
+			name:      "persisted queue 7.x",
+			responses: map[string][]byte{"/_node/stats": queueV7JSON},
+			expected:  merge(expected, map[string]float64{`logstash_node_queued_events`: 42}),
+		},
+	})
+}
+
+func merge(maps ...map[string]float64) map[string]float64 {
+	merged := make(map[string]float64)
+	for _, m := range maps {
+		for key, value := range m {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+var dlQueueV8JSON = []byte(`
+{
+  "version": "8.4.0",
+  "pipelines": {
+    "main": {
+      "dead_letter_queue": {
+        "max_queue_size_in_bytes": 1073741824,
+        "last_error": "no errors",
+        "queue_size_in_bytes": 0,
+        "dropped_events": 12,
+        "expired_events": 3,
+        "storage_policy": "drop_newer"
+      }
+    },
+    "no-dlq": {}
+  }
+}
+`)
+
+func TestDeadLetterQueueStats(t *testing.T) {
+	runCollectorTests(t, NewNodeStatsCollector, []collectorTest{
+		{
+			name:      "dead letter queue",
+			responses: map[string][]byte{"/_node/stats": dlQueueV8JSON},
+			expected: map[string]float64{
+				`logstash_node_dead_letter_queue_size_bytes{pipeline="main"}`:                                              0,
+				`logstash_node_dead_letter_queue_max_size_bytes{pipeline="main"}`:                                          1073741824,
+				`logstash_node_dead_letter_queue_dropped_events_total{pipeline="main"}`:                                    12,
+				`logstash_node_dead_letter_queue_expired_events_total{pipeline="main"}`:                                    3,
+				`logstash_node_dead_letter_queue_info{last_error="no errors",pipeline="main",storage_policy="drop_newer"}`: 1,
+			},
+		},
+	})
+}
+
+var processJSON = []byte(`
+{
+  "version": "7.17.0",
+  "process": {
+    "open_file_descriptors": 164,
+    "peak_open_file_descriptors": 166,
+    "max_file_descriptors": 10240,
+    "mem": {"total_virtual_in_bytes": 5399474176},
+    "cpu": {
+      "total_in_millis": 72810537,
+      "percent": 12,
+      "load_average": {"1m": 2.5, "5m": 1.75, "15m": 1.25}
+    }
+  },
+  "os": {
+    "cgroup": {
+      "cpuacct": {"control_group": "/docker/logstash", "usage_nanos": 378477588075},
+      "cpu": {
+        "control_group": "/docker/logstash",
+        "cfs_period_micros": 100000,
+        "cfs_quota_micros": 200000,
+        "stat": {
+          "number_of_elapsed_periods": 4157,
+          "number_of_times_throttled": 460,
+          "time_throttled_nanos": 581617440337
+        }
+      }
+    }
+  },
+  "pipelines": {}
+}
+`)
+
+func TestProcessAndOsStats(t *testing.T) {
+	runCollectorTests(t, NewNodeStatsCollector, []collectorTest{
+		{
+			name:      "process and os",
+			responses: map[string][]byte{"/_node/stats": processJSON},
+			expected: map[string]float64{
+				`logstash_node_process_cpu_percent`:                                                         12,
+				`logstash_node_process_cpu_load_average_1m`:                                                 2.5,
+				`logstash_node_process_cpu_load_average_5m`:                                                 1.75,
+				`logstash_node_process_cpu_load_average_15m`:                                                1.25,
+				`logstash_node_process_peak_open_filedescriptors`:                                           166,
+				`logstash_node_os_cgroup_cpuacct_usage_seconds_total{control_group="/docker/logstash"}`:     378.477588075,
+				`logstash_node_os_cgroup_cpu_cfs_period_seconds{control_group="/docker/logstash"}`:          0.1,
+				`logstash_node_os_cgroup_cpu_cfs_quota_seconds{control_group="/docker/logstash"}`:           0.2,
+				`logstash_node_os_cgroup_cpu_cfs_elapsed_periods_total{control_group="/docker/logstash"}`:   4157,
+				`logstash_node_os_cgroup_cpu_cfs_throttled_periods_total{control_group="/docker/logstash"}`: 460,
+				`logstash_node_os_cgroup_cpu_cfs_throttled_seconds_total{control_group="/docker/logstash"}`: 581.617440337,
+			},
+		},
+	})
+}
+
+var jvmStatsJSON = []byte(`
+{
+  "version": "8.6.0",
+  "jvm": {
+    "uptime_in_millis": 123456,
+    "mem": {
+      "heap_used_percent": 42,
+      "pools": {
+        "young": {"peak_used_in_bytes": 100, "used_in_bytes": 10, "peak_max_in_bytes": -1, "max_in_bytes": -1, "committed_in_bytes": 50},
+        "old": {"peak_used_in_bytes": 900, "used_in_bytes": 800, "peak_max_in_bytes": 1000, "max_in_bytes": 1000, "committed_in_bytes": 950},
+        "survivor": {"peak_used_in_bytes": 30, "used_in_bytes": 3, "peak_max_in_bytes": -1, "max_in_bytes": -1, "committed_in_bytes": 20}
+      }
+    },
+    "gc": {
+      "collectors": {
+        "young": {"collection_time_in_millis": 1500, "collection_count": 20},
+        "old": {"collection_time_in_millis": 0, "collection_count": 0}
+      }
+    }
+  }
+}
+`)
+
+var jvmInfoJSON = []byte(`
+{
+  "version": "8.6.0",
+  "jvm": {"gc_collectors": ["G1 Young Generation", "G1 Old Generation"]}
+}
+`)
+
+func TestJvmStats(t *testing.T) {
+	runCollectorTests(t, NewNodeStatsCollector, []collectorTest{
+		{
+			name: "jvm",
+			responses: map[string][]byte{
+				"/_node/stats": jvmStatsJSON,
+				"/_node":       jvmInfoJSON,
+			},
+			expected: map[string]float64{
+				`logstash_node_jvm_uptime_seconds`:                                                                 123.456,
+				`logstash_node_mem_heap_used_percent`:                                                              42,
+				`logstash_node_mem_pool_peak_used_bytes{pool="young"}`:                                             100,
+				`logstash_node_mem_pool_peak_used_bytes{pool="old"}`:                                               900,
+				`logstash_node_mem_pool_peak_used_bytes{pool="survivor"}`:                                          30,
+				`logstash_node_mem_pool_peak_max_bytes{pool="young"}`:                                              -1,
+				`logstash_node_mem_pool_peak_max_bytes{pool="old"}`:                                                1000,
+				`logstash_node_mem_pool_peak_max_bytes{pool="survivor"}`:                                           -1,
+				`logstash_node_mem_pool_committed_bytes{pool="young"}`:                                             50,
+				`logstash_node_mem_pool_committed_bytes{pool="old"}`:                                               950,
+				`logstash_node_mem_pool_committed_bytes{pool="survivor"}`:                                          20,
+				`logstash_node_gc_collection_duration_seconds_total{collector="young",name="G1 Young Generation"}`: 1.5,
+				`logstash_node_gc_collection_duration_seconds_total{collector="old",name="G1 Old Generation"}`:     0,
+				`logstash_node_gc_collection_total{collector="young",name="G1 Young Generation"}`:                  20,
+				`logstash_node_gc_collection_total{collector="old",name="G1 Old Generation"}`:                      0,
+			},
+		},
+		{
+			name:      "jvm without node info",
+			responses: map[string][]byte{"/_node/stats": jvmStatsJSON},
+			expected: map[string]float64{
+				`logstash_node_gc_collection_total{collector="young",name="young"}`: 20,
+				`logstash_node_gc_collection_total{collector="old",name="old"}`:     0,
+			},
+		},
+	})
+}
+
+var eventsJSON = []byte(`
+{
+  "version": "7.17.0",
+  "events": {
+    "in": 300,
+    "filtered": 290,
+    "out": 280,
+    "duration_in_millis": 2500,
+    "queue_push_duration_in_millis": 750
+  },
+  "pipelines": {
+    "main": {
+      "events": {
+        "in": 300,
+        "filtered": 290,
+        "out": 280,
+        "duration_in_millis": 2500,
+        "queue_push_duration_in_millis": 750
+      }
+    }
+  }
+}
+`)
+
+func TestEventsStats(t *testing.T) {
+	runCollectorTests(t, NewNodeStatsCollector, []collectorTest{
+		{
+			name:      "events",
+			responses: map[string][]byte{"/_node/stats": eventsJSON},
+			expected: map[string]float64{
+				`logstash_node_events_in_total`:                                             300,
+				`logstash_node_events_filtered_total`:                                       290,
+				`logstash_node_events_out_total`:                                            280,
+				`logstash_node_events_duration_seconds_total`:                               2.5,
+				`logstash_node_events_queue_push_duration_seconds_total`:                    0.75,
+				`logstash_node_pipeline_queue_push_duration_seconds_total{pipeline="main"}`: 0.75,
+			},
+		},
+		{
+			name:      "events on logstash 5",
+			responses: map[string][]byte{"/_node/stats": queueJSON},
+			absent:    []string{"logstash_node_events_"},
+		},
+	})
+}
+
+var pluginMetricsJSON = []byte(`
+{
+  "version": "7.17.0",
+  "pipelines": {
+    "main": {
+      "plugins": {
+        "inputs": [{
+          "id": "beats-in",
+          "name": "beats",
+          "events": {"out": 100},
+          "current_connections": 4,
+          "peak_connections": 9
+        }],
+        "filters": [{
+          "id": "grok-1",
+          "name": "grok",
+          "events": {"in": 100, "out": 100},
+          "matches": 90,
+          "failures": 10,
+          "patterns_per_field": {"message": 1},
+          "flow": {"worker_utilization": {"current": 0.5}}
+        }],
+        "outputs": [{
+          "id": "custom-out",
+          "name": "custom",
+          "events": {"in": 100, "out": 100},
+          "state": "ok",
+          "retries": {"total": 3, "last": [1, 2]}
+        }]
+      }
+    }
+  }
+}
+`)
+
+func TestPluginMetrics(t *testing.T) {
+	runCollectorTests(t, NewNodeStatsCollector, []collectorTest{
+		{
+			name:      "plugin metrics",
+			responses: map[string][]byte{"/_node/stats": pluginMetricsJSON},
+			expected: map[string]float64{
+				`logstash_node_plugin_metric{key="patterns_per_field.message",pipeline="main",plugin="grok",plugin_id="grok-1",plugin_type="filter"}`: 1,
+				`logstash_node_plugin_metric{key="retries.total",pipeline="main",plugin="custom",plugin_id="custom-out",plugin_type="output"}`:        3,
+				`logstash_node_plugin_matches_total{pipeline="main",plugin="grok",plugin_id="grok-1",plugin_type="filter"}`:                           90,
+				`logstash_node_plugin_failures_total{pipeline="main",plugin="grok",plugin_id="grok-1",plugin_type="filter"}`:                          10,
+				`logstash_node_plugin_current_connections{pipeline="main",plugin="beats",plugin_id="beats-in",plugin_type="input"}`:                   4,
+				`logstash_node_plugin_peak_connections{pipeline="main",plugin="beats",plugin_id="beats-in",plugin_type="input"}`:                      9,
+			},
+			absent: []string{
+				`logstash_node_plugin_metric{key="events`,
+				`logstash_node_plugin_metric{key="flow`,
+				`logstash_node_plugin_metric{key="state"`,
+				`logstash_node_plugin_metric{key="matches"`,
+				`logstash_node_plugin_metric{key="failures"`,
+			},
+		},
+	})
+}
+
+var wellKnownPluginsJSON = []byte(`
+{
+  "version": "8.6.0",
+  "pipelines": {
+    "main": {
+      "plugins": {
+        "inputs": [
+          {"id": "beats-in", "name": "beats", "events": {"out": 100}, "current_connections": 4, "peak_connections": 9},
+          {"id": "from-upstream", "name": "pipeline", "events": {"out": 50}}
+        ],
+        "filters": [
+          {"id": "date-1", "name": "date", "events": {"in": 100, "out": 100}, "matches": 97, "failures": 3},
+          {"id": "dissect-1", "name": "dissect", "events": {"in": 100, "out": 100}, "matches": 99, "failures": 1}
+        ],
+        "outputs": [
+          {
+            "id": "es-out",
+            "name": "elasticsearch",
+            "events": {"in": 100, "out": 98},
+            "bulk_requests": {
+              "successes": 10,
+              "failures": 1,
+              "with_errors": 2,
+              "responses": {"200": 10, "429": 3}
+            },
+            "documents": {"successes": 95, "non_retryable_failures": 3}
+          },
+          {"id": "to-downstream", "name": "pipeline", "events": {"in": 60, "out": 60}}
+        ]
+      }
+    }
+  }
+}
+`)
+
+func TestWellKnownPluginMetrics(t *testing.T) {
+	es := `pipeline="main",plugin="elasticsearch",plugin_id="es-out",plugin_type="output"`
+
+	runCollectorTests(t, NewNodeStatsCollector, []collectorTest{
+		{
+			name:      "well-known plugins",
+			responses: map[string][]byte{"/_node/stats": wellKnownPluginsJSON},
+			expected: map[string]float64{
+				`logstash_node_plugin_elasticsearch_bulk_requests_successes_total{` + es + `}`:                                                         10,
+				`logstash_node_plugin_elasticsearch_bulk_requests_failures_total{` + es + `}`:                                                          1,
+				`logstash_node_plugin_elasticsearch_bulk_requests_with_errors_total{` + es + `}`:                                                       2,
+				`logstash_node_plugin_elasticsearch_bulk_requests_responses_total{code="200",` + es + `}`:                                              10,
+				`logstash_node_plugin_elasticsearch_bulk_requests_responses_total{code="429",` + es + `}`:                                              3,
+				`logstash_node_plugin_elasticsearch_documents_successes_total{` + es + `}`:                                                             95,
+				`logstash_node_plugin_elasticsearch_documents_non_retryable_failures_total{` + es + `}`:                                                3,
+				`logstash_node_plugin_current_connections{pipeline="main",plugin="beats",plugin_id="beats-in",plugin_type="input"}`:                    4,
+				`logstash_node_plugin_peak_connections{pipeline="main",plugin="beats",plugin_id="beats-in",plugin_type="input"}`:                       9,
+				`logstash_node_plugin_pipeline_events_received_total{pipeline="main",plugin="pipeline",plugin_id="from-upstream",plugin_type="input"}`: 50,
+				`logstash_node_plugin_pipeline_events_sent_total{pipeline="main",plugin="pipeline",plugin_id="to-downstream",plugin_type="output"}`:    60,
+				`logstash_node_plugin_matches_total{pipeline="main",plugin="date",plugin_id="date-1",plugin_type="filter"}`:                            97,
+				`logstash_node_plugin_failures_total{pipeline="main",plugin="date",plugin_id="date-1",plugin_type="filter"}`:                           3,
+				`logstash_node_plugin_matches_total{pipeline="main",plugin="dissect",plugin_id="dissect-1",plugin_type="filter"}`:                      99,
+				`logstash_node_plugin_failures_total{pipeline="main",plugin="dissect",plugin_id="dissect-1",plugin_type="filter"}`:                     1,
+			},
+			absent: []string{"logstash_node_plugin_metric{"},
+		},
+	})
+}
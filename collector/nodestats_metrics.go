@@ -0,0 +1,230 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"reflect"
+)
+
+var (
+	nodeScope = &metricScope{
+		typ: reflect.TypeOf(NodeStatsResponse{}),
+	}
+	memPoolScope = &metricScope{
+		path:   "jvm.mem.pools.<pool>",
+		labels: []string{"pool"},
+		typ:    reflect.TypeOf(MemPool{}),
+	}
+	gcScope = &metricScope{
+		path:   "jvm.gc.collectors.<collector>",
+		labels: []string{"collector", "name"},
+		typ:    reflect.TypeOf(GCCollector{}),
+	}
+	cgroupCPUAcctScope = &metricScope{
+		path:   "os.cgroup.cpuacct",
+		labels: []string{"control_group"},
+		typ:    reflect.TypeOf(CgroupCPUAcct{}),
+	}
+	cgroupCPUScope = &metricScope{
+		path:   "os.cgroup.cpu",
+		labels: []string{"control_group"},
+		typ:    reflect.TypeOf(CgroupCPU{}),
+	}
+	pipelineScope = &metricScope{
+		path:   "pipelines.<pipeline>",
+		labels: []string{"pipeline"},
+		typ:    reflect.TypeOf(Pipeline{}),
+	}
+	pluginScope = &metricScope{
+		path:   "pipelines.<pipeline>.plugins.<plugin_type>s[]",
+		labels: []string{"pipeline", "plugin", "plugin_id", "plugin_type"},
+		typ:    reflect.TypeOf(Plugin{}),
+	}
+	queueScope = &metricScope{
+		path:   "pipelines.<pipeline>.queue",
+		labels: []string{"pipeline"},
+		typ:    reflect.TypeOf(Queue{}),
+	}
+	queueDataScope = &metricScope{
+		path:   "pipelines.<pipeline>.queue.data",
+		labels: []string{"pipeline", "path", "storage_type"},
+		typ:    reflect.TypeOf(QueueData{}),
+	}
+	deadLetterQueueScope = &metricScope{
+		path:   "pipelines.<pipeline>.dead_letter_queue",
+		labels: []string{"pipeline"},
+		typ:    reflect.TypeOf(DeadLetterQueue{}),
+	}
+)
+
+// nodeMetrics are the metrics read from node stats as they are. Queue metrics
+// are only exported for persisted queues.
+var nodeMetrics = []metricSpec{
+	{scope: nodeScope, path: "jvm.threads.count", name: "jvm_threads_count", valueType: prometheus.GaugeValue,
+		help: "Number of live JVM threads."},
+	{scope: nodeScope, path: "jvm.threads.peak_count", name: "jvm_threads_peak_count", valueType: prometheus.GaugeValue,
+		help: "Highest number of live JVM threads since the JVM started."},
+	{scope: nodeScope, path: "jvm.mem.heap_used_in_bytes", name: "mem_heap_used_bytes", valueType: prometheus.GaugeValue,
+		help: "Heap memory in use."},
+	{scope: nodeScope, path: "jvm.mem.heap_committed_in_bytes", name: "mem_heap_committed_bytes", valueType: prometheus.GaugeValue,
+		help: "Heap memory committed by the JVM."},
+	{scope: nodeScope, path: "jvm.mem.heap_max_in_bytes", name: "mem_heap_max_bytes", valueType: prometheus.GaugeValue,
+		help: "Maximum heap memory the JVM may use."},
+	{scope: nodeScope, path: "jvm.mem.non_heap_used_in_bytes", name: "mem_nonheap_used_bytes", valueType: prometheus.GaugeValue,
+		help: "Non-heap memory in use."},
+	{scope: nodeScope, path: "jvm.mem.non_heap_committed_in_bytes", name: "mem_nonheap_committed_bytes", valueType: prometheus.GaugeValue,
+		help: "Non-heap memory committed by the JVM."},
+	{scope: nodeScope, path: "jvm.mem.heap_used_percent", name: "mem_heap_used_percent", valueType: prometheus.GaugeValue,
+		help: "Percentage of the maximum heap in use."},
+	{scope: nodeScope, path: "jvm.uptime_in_millis", unit: unitMillis, name: "jvm_uptime_seconds", valueType: prometheus.GaugeValue,
+		help: "Time since the JVM running Logstash started."},
+
+	{scope: memPoolScope, path: "peak_used_in_bytes", name: "mem_pool_peak_used_bytes", valueType: prometheus.GaugeValue,
+		help: "Highest memory usage of a JVM memory pool."},
+	{scope: memPoolScope, path: "used_in_bytes", name: "mem_pool_used_bytes", valueType: prometheus.GaugeValue,
+		help: "Memory usage of a JVM memory pool."},
+	{scope: memPoolScope, path: "peak_max_in_bytes", name: "mem_pool_peak_max_bytes", valueType: prometheus.GaugeValue,
+		help: "Highest maximum size of a JVM memory pool."},
+	{scope: memPoolScope, path: "max_in_bytes", name: "mem_pool_max_bytes", valueType: prometheus.GaugeValue,
+		help: "Maximum size of a JVM memory pool."},
+	{scope: memPoolScope, path: "committed_in_bytes", name: "mem_pool_committed_bytes", valueType: prometheus.GaugeValue,
+		help: "Memory committed by the JVM to a memory pool."},
+
+	{scope: gcScope, path: "collection_time_in_millis", unit: unitMillis, name: "gc_collection_duration_seconds_total", valueType: prometheus.CounterValue,
+		help: "Time a JVM garbage collector has spent collecting."},
+	{scope: gcScope, path: "collection_count", name: "gc_collection_total", valueType: prometheus.CounterValue,
+		help: "Number of collections of a JVM garbage collector."},
+
+	{scope: nodeScope, path: "process.open_file_descriptors", name: "process_open_filedescriptors", valueType: prometheus.GaugeValue,
+		help: "Number of file descriptors the Logstash process has open."},
+	{scope: nodeScope, path: "process.max_file_descriptors", name: "process_max_filedescriptors", valueType: prometheus.GaugeValue,
+		help: "Maximum number of file descriptors the Logstash process may open."},
+	{scope: nodeScope, path: "process.peak_open_file_descriptors", name: "process_peak_open_filedescriptors", valueType: prometheus.GaugeValue,
+		help: "Highest number of file descriptors the Logstash process has had open."},
+	{scope: nodeScope, path: "process.mem.total_virtual_in_bytes", name: "process_mem_total_virtual_bytes", valueType: prometheus.GaugeValue,
+		help: "Virtual memory size of the Logstash process."},
+	{scope: nodeScope, path: "process.cpu.total_in_millis", unit: unitMillis, name: "process_cpu_total_seconds_total", valueType: prometheus.CounterValue,
+		help: "CPU time used by the Logstash process."},
+	{scope: nodeScope, path: "process.cpu.percent", name: "process_cpu_percent", valueType: prometheus.GaugeValue,
+		help: "Recent CPU usage of the Logstash process in percent."},
+	{scope: nodeScope, path: "process.cpu.load_average.1m", name: "process_cpu_load_average_1m", valueType: prometheus.GaugeValue,
+		help: "System load average over the last minute."},
+	{scope: nodeScope, path: "process.cpu.load_average.5m", name: "process_cpu_load_average_5m", valueType: prometheus.GaugeValue,
+		help: "System load average over the last 5 minutes."},
+	{scope: nodeScope, path: "process.cpu.load_average.15m", name: "process_cpu_load_average_15m", valueType: prometheus.GaugeValue,
+		help: "System load average over the last 15 minutes."},
+
+	{scope: cgroupCPUAcctScope, path: "usage_nanos", unit: unitNanos, name: "os_cgroup_cpuacct_usage_seconds_total", valueType: prometheus.CounterValue,
+		help: "CPU time consumed by all tasks in the cgroup of Logstash."},
+	{scope: cgroupCPUScope, path: "cfs_period_micros", unit: unitMicros, name: "os_cgroup_cpu_cfs_period_seconds", valueType: prometheus.GaugeValue,
+		help: "Period of the CFS bandwidth control of the cgroup of Logstash."},
+	{scope: cgroupCPUScope, path: "cfs_quota_micros", unit: unitMicros, positive: true, name: "os_cgroup_cpu_cfs_quota_seconds", valueType: prometheus.GaugeValue,
+		help: "CPU time the cgroup of Logstash may use per CFS period. Not reported if unlimited."},
+	{scope: cgroupCPUScope, path: "stat.number_of_elapsed_periods", name: "os_cgroup_cpu_cfs_elapsed_periods_total", valueType: prometheus.CounterValue,
+		help: "Number of CFS periods elapsed for the cgroup of Logstash."},
+	{scope: cgroupCPUScope, path: "stat.number_of_times_throttled", name: "os_cgroup_cpu_cfs_throttled_periods_total", valueType: prometheus.CounterValue,
+		help: "Number of CFS periods the cgroup of Logstash has been throttled in."},
+	{scope: cgroupCPUScope, path: "stat.time_throttled_nanos", unit: unitNanos, name: "os_cgroup_cpu_cfs_throttled_seconds_total", valueType: prometheus.CounterValue,
+		help: "Total time the cgroup of Logstash has been throttled for."},
+
+	{scope: nodeScope, path: "reloads.successes", name: "reloads_successes_total", valueType: prometheus.CounterValue,
+		help: "Number of successful config reloads of all pipelines."},
+	{scope: nodeScope, path: "reloads.failures", name: "reloads_failures_total", valueType: prometheus.CounterValue,
+		help: "Number of failed config reloads of all pipelines."},
+
+	{scope: nodeScope, path: "events.in", name: "events_in_total", valueType: prometheus.CounterValue,
+		help: "Number of events received by the inputs of all pipelines."},
+	{scope: nodeScope, path: "events.filtered", name: "events_filtered_total", valueType: prometheus.CounterValue,
+		help: "Number of events processed by the filters of all pipelines."},
+	{scope: nodeScope, path: "events.out", name: "events_out_total", valueType: prometheus.CounterValue,
+		help: "Number of events sent by the outputs of all pipelines."},
+	{scope: nodeScope, path: "events.duration_in_millis", unit: unitMillis, name: "events_duration_seconds_total", valueType: prometheus.CounterValue,
+		help: "Time the filters and outputs of all pipelines have spent processing events."},
+	{scope: nodeScope, path: "events.queue_push_duration_in_millis", unit: unitMillis, name: "events_queue_push_duration_seconds_total", valueType: prometheus.CounterValue,
+		help: "Time the inputs of all pipelines have spent pushing events into their queues."},
+	{scope: nodeScope, path: "queue.events_count", name: "queued_events", valueType: prometheus.GaugeValue,
+		help: "Number of events in the queues of all pipelines."},
+
+	{scope: pipelineScope, path: "events.duration_in_millis", unit: unitMillis, name: "pipeline_duration_seconds_total", valueType: prometheus.CounterValue,
+		help: "Time the filters and outputs of a pipeline have spent processing events."},
+	{scope: pipelineScope, path: "events.in", name: "pipeline_events_in_total", valueType: prometheus.CounterValue,
+		help: "Number of events received by the inputs of a pipeline."},
+	{scope: pipelineScope, path: "events.filtered", name: "pipeline_events_filtered_total", valueType: prometheus.CounterValue,
+		help: "Number of events processed by the filters of a pipeline."},
+	{scope: pipelineScope, path: "events.out", name: "pipeline_events_out_total", valueType: prometheus.CounterValue,
+		help: "Number of events sent by the outputs of a pipeline."},
+	{scope: pipelineScope, path: "events.queue_push_duration_in_millis", unit: unitMillis, name: "pipeline_queue_push_duration_seconds_total", valueType: prometheus.CounterValue,
+		help: "Time the inputs of a pipeline have spent pushing events into its queue."},
+	{scope: pipelineScope, path: "reloads.successes", name: "pipeline_reloads_successes_total", valueType: prometheus.CounterValue,
+		help: "Number of successful config reloads of a pipeline."},
+	{scope: pipelineScope, path: "reloads.failures", name: "pipeline_reloads_failures_total", valueType: prometheus.CounterValue,
+		help: "Number of failed config reloads of a pipeline."},
+	{scope: pipelineScope, path: "reloads.last_success_timestamp", unit: unitTimestamp, name: "pipeline_reloads_last_success_timestamp_seconds", valueType: prometheus.GaugeValue,
+		help: "Unix timestamp of the last successful config reload of a pipeline."},
+	{scope: pipelineScope, path: "reloads.last_failure_timestamp", unit: unitTimestamp, name: "pipeline_reloads_last_failure_timestamp_seconds", valueType: prometheus.GaugeValue,
+		help: "Unix timestamp of the last failed config reload of a pipeline."},
+
+	{scope: pluginScope, path: "events.duration_in_millis", unit: unitMillis, pluginTypes: []string{"filter"}, name: "plugin_duration_seconds_total", valueType: prometheus.CounterValue,
+		help: "Time a filter has spent processing events."},
+	{scope: pluginScope, path: "events.in", name: "plugin_events_in_total", valueType: prometheus.CounterValue,
+		help: "Number of events received by a plugin."},
+	{scope: pluginScope, path: "events.out", name: "plugin_events_out_total", valueType: prometheus.CounterValue,
+		help: "Number of events sent on by a plugin."},
+	{scope: pluginScope, path: "matches", pluginTypes: []string{"filter"}, name: "plugin_matches_total", valueType: prometheus.CounterValue,
+		help: "Number of events a filter such as grok matched."},
+	{scope: pluginScope, path: "failures", pluginTypes: []string{"filter"}, name: "plugin_failures_total", valueType: prometheus.CounterValue,
+		help: "Number of events a filter such as grok failed to match."},
+
+	{scope: queueScope, path: "events_count", name: "queue_events", valueType: prometheus.GaugeValue,
+		help: "Number of events in the persisted queue of a pipeline."},
+	{scope: queueScope, path: "capacity.page_capacity_in_bytes", name: "queue_page_capacity_bytes", valueType: prometheus.GaugeValue,
+		help: "Size of the pages of the persisted queue of a pipeline."},
+	{scope: queueScope, path: "max_queue_size_in_bytes", name: "queue_max_size_bytes", valueType: prometheus.GaugeValue,
+		help: "Maximum size of the persisted queue of a pipeline."},
+	{scope: queueScope, path: "capacity.max_unread_events", name: "queue_max_unread_events", valueType: prometheus.GaugeValue,
+		help: "Maximum number of unread events in the persisted queue of a pipeline, unlimited if 0."},
+	{scope: queueScope, path: "queue_size_in_bytes", name: "queue_size_bytes", valueType: prometheus.GaugeValue,
+		help: "Disk space used by the persisted queue of a pipeline."},
+	{scope: queueDataScope, path: "free_space_in_bytes", name: "queue_free_space_bytes", valueType: prometheus.GaugeValue,
+		help: "Free space on the filesystem holding the persisted queue of a pipeline."},
+
+	{scope: deadLetterQueueScope, path: "queue_size_in_bytes", name: "dead_letter_queue_size_bytes", valueType: prometheus.GaugeValue,
+		help: "Size of the dead letter queue of a pipeline."},
+	{scope: deadLetterQueueScope, path: "max_queue_size_in_bytes", name: "dead_letter_queue_max_size_bytes", valueType: prometheus.GaugeValue,
+		help: "Maximum size of the dead letter queue of a pipeline."},
+	{scope: deadLetterQueueScope, path: "dropped_events", name: "dead_letter_queue_dropped_events_total", valueType: prometheus.CounterValue,
+		help: "Number of events dropped because the dead letter queue of a pipeline was full."},
+	{scope: deadLetterQueueScope, path: "expired_events", name: "dead_letter_queue_expired_events_total", valueType: prometheus.CounterValue,
+		help: "Number of events removed from the dead letter queue of a pipeline by its age retention policy."},
+}
+
+// Metrics whose label values are read from node stats
+var (
+	schemaAdapterInfo = metricDef{
+		name:      "schema_adapter_info",
+		help:      "A metric with a constant '1' value labeled by the Logstash version and the adapter its node stats are read with.",
+		valueType: prometheus.GaugeValue,
+		labels:    []string{"adapter", "version"},
+		source:    "version",
+	}
+	pipelineReloadsLastErrorInfo = metricDef{
+		name:      "pipeline_reloads_last_error_info",
+		help:      "A metric with a constant '1' value labeled by the truncated message and backtrace class of the last config reload error of a pipeline.",
+		valueType: prometheus.GaugeValue,
+		labels:    []string{"pipeline", "message", "backtrace_class"},
+		source:    "pipelines.<pipeline>.reloads.last_error",
+	}
+	deadLetterQueueInfo = metricDef{
+		name:      "dead_letter_queue_info",
+		help:      "A metric with a constant '1' value labeled by the storage policy and truncated last error of the dead letter queue of a pipeline.",
+		valueType: prometheus.GaugeValue,
+		labels:    []string{"pipeline", "storage_policy", "last_error"},
+		source:    "pipelines.<pipeline>.dead_letter_queue",
+	}
+	pluginMetric = metricDef{
+		name:      "plugin_metric",
+		help:      "Plugin-specific numeric stat, such as the current connections of beats inputs, identified by its dotted key.",
+		valueType: prometheus.UntypedValue,
+		labels:    []string{"pipeline", "plugin", "plugin_id", "plugin_type", "key"},
+		source:    pluginScope.path + ".<key>",
+	}
+)
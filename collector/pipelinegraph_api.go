@@ -0,0 +1,65 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// PipelineGraphResponse type
+type PipelineGraphResponse struct {
+	Host      string `json:"host"`
+	Version   string `json:"version"`
+	Pipelines map[string]struct {
+		Graph struct {
+			Graph PipelineGraph `json:"graph"`
+			Type  string        `json:"type"`
+			Hash  string        `json:"hash"`
+		} `json:"graph"`
+	} `json:"pipelines"`
+}
+
+// PipelineGraph is the compiled graph of a pipeline, as reported by Logstash
+// >=6.3
+type PipelineGraph struct {
+	Vertices []GraphVertex `json:"vertices"`
+	Edges    []GraphEdge   `json:"edges"`
+}
+
+// GraphVertex is a plugin, queue or conditional of a pipeline graph
+type GraphVertex struct {
+	ID         string `json:"id"`
+	ExplicitID bool   `json:"explicit_id"`
+	Type       string `json:"type"` // plugin, queue or if
+	PluginType string `json:"plugin_type"`
+	ConfigName string `json:"config_name"`
+	Condition  string `json:"condition"`
+}
+
+// GraphEdge connects two vertices of a pipeline graph. Edges leaving a
+// conditional are of type boolean and taken when the condition is When.
+type GraphEdge struct {
+	ID   string `json:"id"`
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"` // plain or boolean
+	When *bool  `json:"when"`
+}
+
+// NodePipelineGraph function
+func NodePipelineGraph(ctx context.Context, target *Target, pipelineID string) (PipelineGraph, error) {
+	var response PipelineGraphResponse
+
+	handler := target.handler("pipeline_graph", fmt.Sprintf("/_node/pipelines/%s?graph=true", url.PathEscape(pipelineID)))
+
+	if err := getMetrics(ctx, handler, &response); err != nil {
+		return PipelineGraph{}, err
+	}
+
+	pipeline, ok := response.Pipelines[pipelineID]
+	if !ok {
+		return PipelineGraph{}, fmt.Errorf("pipeline %q not found", pipelineID)
+	}
+
+	return pipeline.Graph.Graph, nil
+}
@@ -0,0 +1,23 @@
+package collector
+
+import "context"
+
+// PluginsResponse type
+type PluginsResponse struct {
+	Total   int `json:"total"`
+	Plugins []struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"plugins"`
+}
+
+// Plugins function
+func Plugins(ctx context.Context, target *Target) (PluginsResponse, error) {
+	var response PluginsResponse
+
+	handler := target.handler("plugins", "/_node/plugins")
+
+	err := getMetrics(ctx, handler, &response)
+
+	return response, err
+}
@@ -0,0 +1,76 @@
+package collector
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// PluginsCollector type
+type PluginsCollector struct {
+	target *Target
+
+	PluginInfos      *prometheus.Desc
+	PluginsInstalled *prometheus.Desc
+}
+
+// NewPluginsCollector function
+func NewPluginsCollector(target *Target) (Collector, error) {
+	return &PluginsCollector{
+		target: target,
+
+		PluginInfos: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, "plugin", "info"),
+			"A metric with a constant '1' value labeled by name and version of an installed plugin.",
+			[]string{"name", "version"},
+			nil,
+		),
+
+		PluginsInstalled: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, "", "plugins_installed"),
+			"Number of installed plugins.",
+			nil,
+			nil,
+		),
+	}, nil
+}
+
+// Collect function implements plugins_collector collector
+func (c *PluginsCollector) Collect(ctx context.Context, ch chan<- prometheus.Metric) error {
+	if desc, err := c.collect(ctx, ch); err != nil {
+		log.Error("Failed collecting plugins metrics", desc, err)
+		return err
+	}
+	return nil
+}
+
+func (c *PluginsCollector) collect(ctx context.Context, ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
+	stats, err := Plugins(ctx, c.target)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(stats.Plugins))
+	for _, plugin := range stats.Plugins {
+		if seen[plugin.Name] {
+			continue
+		}
+		seen[plugin.Name] = true
+
+		ch <- prometheus.MustNewConstMetric(
+			c.PluginInfos,
+			prometheus.GaugeValue,
+			float64(1),
+			plugin.Name,
+			plugin.Version,
+		)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.PluginsInstalled,
+		prometheus.GaugeValue,
+		float64(stats.Total),
+	)
+
+	return nil, nil
+}
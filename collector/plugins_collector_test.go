@@ -0,0 +1,31 @@
+package collector
+
+import "testing"
+
+var pluginsJSON = []byte(`
+{
+  "host": "logstash-1",
+  "version": "7.17.0",
+  "total": 3,
+  "plugins": [
+    {"name": "logstash-codec-json", "version": "3.1.0"},
+    {"name": "logstash-input-beats", "version": "6.2.6"},
+    {"name": "logstash-output-elasticsearch", "version": "11.4.1"}
+  ]
+}
+`)
+
+func TestPlugins(t *testing.T) {
+	runCollectorTests(t, NewPluginsCollector, []collectorTest{
+		{
+			name:      "plugins",
+			responses: map[string][]byte{"/_node/plugins": pluginsJSON},
+			expected: map[string]float64{
+				`logstash_plugin_info{name="logstash-codec-json",version="3.1.0"}`:            1,
+				`logstash_plugin_info{name="logstash-input-beats",version="6.2.6"}`:           1,
+				`logstash_plugin_info{name="logstash-output-elasticsearch",version="11.4.1"}`: 1,
+				`logstash_plugins_installed`: 3,
+			},
+		},
+	})
+}
@@ -0,0 +1,128 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"github.com/prometheus/common/log"
+	"sync"
+	"time"
+)
+
+// Poller polls the node stats of a Target on its own interval and keeps the
+// last successfully decoded response, so scrapes do not wait for Logstash
+type Poller struct {
+	target   *Target
+	interval time.Duration
+	timeout  time.Duration
+
+	mtx       sync.Mutex
+	stats     *NodeStatsResponse
+	updated   time.Time
+	failures  int
+	lastError error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewPoller returns a Poller fetching the node stats of target every
+// interval, giving up on a poll after timeout unless it is 0
+func NewPoller(target *Target, interval, timeout time.Duration) *Poller {
+	return &Poller{
+		target:   target,
+		interval: interval,
+		timeout:  timeout,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start polls right away and then on every interval until Stop is called
+func (p *Poller) Start() {
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			p.poll()
+
+			select {
+			case <-ticker.C:
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops polling and waits for a poll in progress to finish
+func (p *Poller) Stop() {
+	close(p.stop)
+	<-p.done
+}
+
+func (p *Poller) poll() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if p.timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), p.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	defer cancel()
+
+	stats, err := NodeStats(ctx, p.target)
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if err != nil {
+		log.Errorf("Polling %s failed: %v", p.target.Endpoint, err)
+		p.failures++
+		p.lastError = err
+		return
+	}
+
+	p.stats = &stats
+	p.updated = time.Now()
+	p.failures = 0
+	p.lastError = nil
+}
+
+// adopt takes over the snapshot and failures of previous
+func (p *Poller) adopt(previous *Poller) {
+	previous.mtx.Lock()
+	stats, updated, failures, lastError := previous.stats, previous.updated, previous.failures, previous.lastError
+	previous.mtx.Unlock()
+
+	p.mtx.Lock()
+	p.stats, p.updated, p.failures, p.lastError = stats, updated, failures, lastError
+	p.mtx.Unlock()
+}
+
+// Snapshot returns the last successfully polled node stats and when they were
+// polled. It fails if no poll has succeeded yet.
+func (p *Poller) Snapshot() (NodeStatsResponse, time.Time, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.stats == nil {
+		if p.lastError != nil {
+			return NodeStatsResponse{}, time.Time{}, p.lastError
+		}
+		return NodeStatsResponse{}, time.Time{}, fmt.Errorf("no node stats polled from %s yet", p.target.Endpoint)
+	}
+
+	return *p.stats, p.updated, nil
+}
+
+// ConsecutiveFailures returns how many polls in a row have failed
+func (p *Poller) ConsecutiveFailures() int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.failures
+}
@@ -0,0 +1,90 @@
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPollerKeepsLastKnownGood(t *testing.T) {
+	var failing int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"version": "6.2.4"}`))
+	}))
+	defer server.Close()
+
+	p := NewPoller(NewTarget(server.URL), time.Minute, time.Second)
+
+	if _, _, err := p.Snapshot(); err == nil {
+		t.Error("expected an error before the first poll")
+	}
+
+	p.poll()
+	stats, updated, err := p.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Version != "6.2.4" {
+		t.Errorf("expected version 6.2.4, got %q", stats.Version)
+	}
+
+	atomic.StoreInt32(&failing, 1)
+	p.poll()
+	p.poll()
+
+	stats, stillUpdated, err := p.Snapshot()
+	if err != nil {
+		t.Fatalf("expected the last known good snapshot, got %v", err)
+	}
+	if stats.Version != "6.2.4" || !stillUpdated.Equal(updated) {
+		t.Errorf("expected the snapshot from the first poll, got %+v from %s", stats, stillUpdated)
+	}
+	if n := p.ConsecutiveFailures(); n != 2 {
+		t.Errorf("expected 2 consecutive failures, got %d", n)
+	}
+
+	atomic.StoreInt32(&failing, 0)
+	p.poll()
+	if n := p.ConsecutiveFailures(); n != 0 {
+		t.Errorf("expected failures to reset after a successful poll, got %d", n)
+	}
+}
+
+func TestPollerStartStop(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/_node/stats" {
+			atomic.AddInt32(&requests, 1)
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	p := NewPoller(NewTarget(server.URL), time.Hour, time.Second)
+	p.Start()
+	p.Stop()
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("expected a poll right on start, got %d requests", n)
+	}
+}
+
+func TestPollerWithoutTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"version": "6.2.4"}`))
+	}))
+	defer server.Close()
+
+	p := NewPoller(NewTarget(server.URL), time.Minute, 0)
+	p.poll()
+
+	if _, _, err := p.Snapshot(); err != nil {
+		t.Errorf("expected a poll without timeout to succeed, got %v", err)
+	}
+}
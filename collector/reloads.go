@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"github.com/prometheus/common/log"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxErrorLabelLength bounds the length of error messages used as label
+// values
+const maxErrorLabelLength = 200
+
+var (
+	// javaFrameRegexp matches frames like
+	// org.logstash.config.ir.ConfigCompiler.compileSources(ConfigCompiler.java:50)
+	javaFrameRegexp = regexp.MustCompile(`^([\w$]+(?:\.[\w$]+)*)\.[\w$<>]+\(`)
+	// rubyFrameRegexp matches frames like
+	// /usr/share/logstash/logstash-core/lib/logstash/compiler.rb:41:in `compile_imperative'
+	rubyFrameRegexp = regexp.MustCompile(`^(.+?\.rb):\d+:in `)
+)
+
+// parseTimestamp parses the ISO 8601 timestamps of the reloads section into
+// Unix seconds
+func parseTimestamp(timestamp *string) (float64, bool) {
+	if timestamp == nil || *timestamp == "" {
+		return 0, false
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, *timestamp)
+	if err != nil {
+		log.Debugf("Cannot parse timestamp %q: %v", *timestamp, err)
+		return 0, false
+	}
+
+	return float64(t.UnixNano()) / float64(time.Second), true
+}
+
+// backtraceClass returns the class raising a reload error from the top frame
+// of its backtrace: the class name for Java frames, or the file below lib/
+// without extension, e.g. logstash/compiler, for Ruby frames
+func backtraceClass(backtrace []string) string {
+	if len(backtrace) == 0 {
+		return ""
+	}
+
+	frame := strings.TrimSpace(backtrace[0])
+	if match := rubyFrameRegexp.FindStringSubmatch(frame); match != nil {
+		file := match[1]
+		if i := strings.LastIndex(file, "/lib/"); i >= 0 {
+			file = file[i+len("/lib/"):]
+		} else {
+			file = path.Base(file)
+		}
+		return strings.TrimSuffix(file, ".rb")
+	}
+	if match := javaFrameRegexp.FindStringSubmatch(frame); match != nil {
+		return match[1]
+	}
+
+	return ""
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,130 @@
+package collector
+
+import (
+	"context"
+	"github.com/prometheus/common/log"
+	"strconv"
+	"strings"
+)
+
+// schemaAdapter normalizes the node stats of a Logstash major version into
+// the layout of the latest one, which is what the collectors read
+type schemaAdapter struct {
+	Name      string
+	normalize func(*NodeStatsResponse)
+}
+
+const (
+	oldestMajor = 5
+	latestMajor = 9
+)
+
+// schemaAdapters by major version. The adapters of Logstash 7 to 9
+// deliberately normalize nothing: Logstash 8 and 9 only add to the layout of
+// Logstash 7, which is the layout the collectors read.
+var schemaAdapters = map[int]schemaAdapter{
+	5: {Name: "logstash5", normalize: normalizeV5},
+	6: {Name: "logstash6", normalize: normalizeV6},
+	7: {Name: "logstash7"},
+	8: {Name: "logstash8"},
+	9: {Name: "logstash9"},
+}
+
+// schemaAdapterFor returns the adapter for a Logstash version. Versions
+// older or newer than those known are handled by the oldest and the latest
+// adapter respectively.
+func schemaAdapterFor(version string) (schemaAdapter, bool) {
+	major, err := strconv.Atoi(strings.SplitN(version, ".", 2)[0])
+	if err != nil {
+		return schemaAdapter{}, false
+	}
+
+	switch {
+	case major < oldestMajor:
+		major = oldestMajor
+	case major > latestMajor:
+		major = latestMajor
+	}
+	return schemaAdapters[major], true
+}
+
+// normalize rewrites stats into the layout of the latest Logstash version,
+// falling back to the layout of the stats if the version is unknown
+func (t *Target) normalize(ctx context.Context, stats *NodeStatsResponse) {
+	version := t.logstashVersion(ctx, stats)
+
+	adapter, ok := schemaAdapterFor(version)
+	if !ok {
+		if stats.Pipelines == nil {
+			adapter = schemaAdapters[oldestMajor]
+		} else {
+			adapter = schemaAdapters[latestMajor]
+		}
+	}
+
+	if adapter.normalize != nil {
+		adapter.normalize(stats)
+	}
+	stats.Schema, stats.SchemaVersion = adapter.Name, version
+}
+
+// logstashVersion returns the Logstash version of target, taken from node
+// info and falling back to the one in the stats. It is cached for as long as
+// the JVM runs, detected by its uptime, so node info is not requested on
+// every fetch even if it is unavailable.
+func (t *Target) logstashVersion(ctx context.Context, stats *NodeStatsResponse) string {
+	uptime := stats.Jvm.UptimeInMillis
+
+	t.mtx.Lock()
+	t.observeUptime(uptime)
+	if t.version != "" {
+		version := t.version
+		t.mtx.Unlock()
+		return version
+	}
+	t.mtx.Unlock()
+
+	version := stats.Version
+	if info, err := t.nodeInfo(ctx, uptime); err == nil && info.Version != "" {
+		version = info.Version
+	} else if err != nil {
+		log.Debugf("Cannot retrieve Logstash version from node info: %v", err)
+	}
+
+	t.mtx.Lock()
+	t.version = version
+	t.mtx.Unlock()
+
+	return version
+}
+
+// normalizeV5 moves the single pipeline of Logstash 5 to pipelines, under
+// the id Logstash 6 gives it
+func normalizeV5(stats *NodeStatsResponse) {
+	if stats.Pipelines == nil {
+		stats.Pipelines = map[string]Pipeline{"main": stats.Pipeline}
+	}
+	stats.Pipeline = Pipeline{}
+
+	normalizeV6(stats)
+}
+
+// normalizeV6 moves the queue stats Logstash 6 and older report as events
+// and under capacity to where Logstash 7 reports them
+func normalizeV6(stats *NodeStatsResponse) {
+	for id, pipeline := range stats.Pipelines {
+		queue := &pipeline.Queue
+		if queue.EventsCount == nil {
+			events := queue.Events
+			queue.EventsCount = &events
+		}
+		if queue.QueueSizeInBytes == nil {
+			queue.QueueSizeInBytes = queue.Capacity.QueueSizeInBytes
+		}
+		if queue.MaxQueueSizeInBytes == nil {
+			maxSize := queue.Capacity.MaxQueueSizeInBytes
+			queue.MaxQueueSizeInBytes = &maxSize
+		}
+		stats.Pipelines[id] = pipeline
+	}
+}
@@ -0,0 +1,201 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSchemaAdapterFor(t *testing.T) {
+	for version, expected := range map[string]string{
+		"2.4.1":   "logstash5",
+		"5.6.16":  "logstash5",
+		"6.8.23":  "logstash6",
+		"7.17.22": "logstash7",
+		"8.15.3":  "logstash8",
+		"9.1.5":   "logstash9",
+		"10.0.0":  "logstash9",
+	} {
+		adapter, ok := schemaAdapterFor(version)
+		if !ok || adapter.Name != expected {
+			t.Errorf("expected %s to be read with %s, got %q", version, expected, adapter.Name)
+		}
+	}
+
+	if _, ok := schemaAdapterFor(""); ok {
+		t.Error("expected no adapter for an unknown version")
+	}
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	content, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestSchemaAdapters(t *testing.T) {
+	versions := []struct {
+		major   int
+		version string
+	}{
+		{5, "5.6.16"},
+		{6, "6.8.23"},
+		{7, "7.17.22"},
+		{8, "8.15.3"},
+		{9, "9.1.5"},
+	}
+
+	var tests []collectorTest
+	for _, v := range versions {
+		major, version := v.major, v.version
+		expected := map[string]float64{
+			fmt.Sprintf(`logstash_node_schema_adapter_info{adapter="logstash%d",version=%q}`, major, version):               1,
+			`logstash_node_pipeline_events_in_total{pipeline="main"}`:                                                       100,
+			`logstash_node_pipeline_events_filtered_total{pipeline="main"}`:                                                 95,
+			`logstash_node_pipeline_events_out_total{pipeline="main"}`:                                                      90,
+			`logstash_node_plugin_matches_total{pipeline="main",plugin="grok",plugin_id="grok",plugin_type="filter"}`:       90,
+			`logstash_node_queue_events{pipeline="main"}`:                                                                   42,
+			`logstash_node_queue_max_size_bytes{pipeline="main"}`:                                                           1073741824,
+			`logstash_node_queue_free_space_bytes{path="/var/lib/logstash/queue/main",pipeline="main",storage_type="ext4"}`: 936886480896,
+			`logstash_node_gc_collection_total{collector="young",name="G1 Young Generation"}`:                               20,
+			`logstash_node_gc_collection_total{collector="old",name="G1 Old Generation"}`:                                   1,
+		}
+		if major >= 6 {
+			expected[`logstash_node_queue_size_bytes{pipeline="main"}`] = 4096
+		}
+
+		tests = append(tests, collectorTest{
+			name: version,
+			responses: map[string][]byte{
+				"/_node/stats": readFixture(t, fmt.Sprintf("node_stats_%d.json", major)),
+				"/_node":       readFixture(t, fmt.Sprintf("node_info_%d.json", major)),
+			},
+			expected: expected,
+		})
+	}
+
+	runCollectorTests(t, NewNodeStatsCollector, tests)
+}
+
+func TestSchemaAdapterWithoutNodeInfo(t *testing.T) {
+	runCollectorTests(t, NewNodeStatsCollector, []collectorTest{
+		{
+			name:      "layout of Logstash 5",
+			responses: map[string][]byte{"/_node/stats": []byte(`{"pipeline": {"events": {"in": 100}}}`)},
+			expected: map[string]float64{
+				`logstash_node_schema_adapter_info{adapter="logstash5",version=""}`: 1,
+				`logstash_node_pipeline_events_in_total{pipeline="main"}`:           100,
+			},
+		},
+	})
+}
+
+func TestSchemaVersionCached(t *testing.T) {
+	var infoRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_node":
+			if atomic.AddInt32(&infoRequests, 1) > 1 {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"version": "8.15.3"}`))
+		case "/_node/stats":
+			w.Write([]byte(`{"version": "8.15.2", "jvm": {"uptime_in_millis": 1000}, "pipelines": {}}`))
+		}
+	}))
+	defer server.Close()
+
+	target := NewTarget(server.URL)
+	for i := 0; i < 3; i++ {
+		stats, err := NodeStats(context.Background(), target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.SchemaVersion != "8.15.3" {
+			t.Errorf("expected the version from node info, got %q", stats.SchemaVersion)
+		}
+	}
+
+	if n := atomic.LoadInt32(&infoRequests); n != 1 {
+		t.Errorf("expected node info to be requested once, got %d requests", n)
+	}
+}
+
+func TestSchemaVersionAfterRestart(t *testing.T) {
+	var mtx sync.Mutex
+	version, uptime := "6.8.23", 5000
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		switch r.URL.Path {
+		case "/_node":
+			fmt.Fprintf(w, `{"version": %q}`, version)
+		case "/_node/stats":
+			fmt.Fprintf(w, `{"version": %q, "jvm": {"uptime_in_millis": %d}, "pipelines": {}}`, version, uptime)
+		}
+	}))
+	defer server.Close()
+
+	target := NewTarget(server.URL)
+	for _, step := range []struct {
+		version string
+		uptime  int
+		schema  string
+	}{
+		{"6.8.23", 5000, "logstash6"},
+		{"6.8.23", 120000, "logstash6"},
+		// Restarted on a newer version, scraped at an uptime above the one
+		// the version was first fetched at
+		{"7.17.22", 60000, "logstash7"},
+	} {
+		mtx.Lock()
+		version, uptime = step.version, step.uptime
+		mtx.Unlock()
+
+		stats, err := NodeStats(context.Background(), target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Schema != step.schema || stats.SchemaVersion != step.version {
+			t.Errorf("at uptime %d: expected %s for %s, got %s for %s", step.uptime, step.schema, step.version, stats.Schema, stats.SchemaVersion)
+		}
+	}
+}
+
+func TestSchemaVersionCachedWithoutNodeInfo(t *testing.T) {
+	var infoRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_node":
+			atomic.AddInt32(&infoRequests, 1)
+			http.Error(w, "forbidden", http.StatusForbidden)
+		case "/_node/stats":
+			w.Write([]byte(`{"version": "7.17.22", "jvm": {"uptime_in_millis": 1000}, "pipelines": {}}`))
+		}
+	}))
+	defer server.Close()
+
+	target := NewTarget(server.URL)
+	for i := 0; i < 3; i++ {
+		stats, err := NodeStats(context.Background(), target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Schema != "logstash7" || stats.SchemaVersion != "7.17.22" {
+			t.Errorf("expected logstash7 for 7.17.22 from node stats, got %s for %q", stats.Schema, stats.SchemaVersion)
+		}
+	}
+
+	if n := atomic.LoadInt32(&infoRequests); n != 1 {
+		t.Errorf("expected node info to be requested once, got %d requests", n)
+	}
+}
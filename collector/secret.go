@@ -0,0 +1,58 @@
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Secret is a credential given either inline or as a file. A file is read
+// again whenever it changes on disk, so secrets can be rotated without a
+// restart.
+type Secret struct {
+	Value string
+	File  string
+
+	mtx     sync.Mutex
+	modTime time.Time
+	size    int64
+	content string
+}
+
+// NewSecret returns a Secret holding value, or reading file if it is set
+func NewSecret(value, file string) *Secret {
+	return &Secret{Value: value, File: file}
+}
+
+// Get returns the current value of the secret
+func (s *Secret) Get() (string, error) {
+	if s.File == "" {
+		return s.Value, nil
+	}
+
+	info, err := os.Stat(s.File)
+	if err != nil {
+		return "", fmt.Errorf("cannot read secret file: %v", err)
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return s.content, nil
+	}
+
+	content, err := ioutil.ReadFile(s.File)
+	if err != nil {
+		return "", fmt.Errorf("cannot read secret file: %v", err)
+	}
+
+	s.modTime = info.ModTime()
+	s.size = info.Size()
+	s.content = strings.TrimSpace(string(content))
+
+	return s.content, nil
+}
@@ -0,0 +1,172 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"regexp"
+	"strings"
+)
+
+// pluginMetricSpec describes a well-known stat of some plugins, exported
+// under its own name and type rather than as logstash_node_plugin_metric.
+// Submatches of key become the values of labels.
+type pluginMetricSpec struct {
+	pluginType string
+	plugins    []string
+	key        *regexp.Regexp
+	name       string
+	help       string
+	valueType  prometheus.ValueType
+	labels     []string
+}
+
+// wellKnownPluginMetrics are the typed stats of common plugins. The matches
+// and failures of filters such as date and dissect are exported for every
+// plugin as plugin_matches_total and plugin_failures_total, and inputs such
+// as kafka and http only report their events.
+var wellKnownPluginMetrics = []pluginMetricSpec{
+	{
+		pluginType: "output",
+		plugins:    []string{"elasticsearch"},
+		key:        regexp.MustCompile(`^bulk_requests\.successes$`),
+		name:       "plugin_elasticsearch_bulk_requests_successes_total",
+		help:       "Number of successful bulk requests of an elasticsearch output.",
+		valueType:  prometheus.CounterValue,
+	},
+	{
+		pluginType: "output",
+		plugins:    []string{"elasticsearch"},
+		key:        regexp.MustCompile(`^bulk_requests\.failures$`),
+		name:       "plugin_elasticsearch_bulk_requests_failures_total",
+		help:       "Number of bulk requests of an elasticsearch output that failed to reach Elasticsearch.",
+		valueType:  prometheus.CounterValue,
+	},
+	{
+		pluginType: "output",
+		plugins:    []string{"elasticsearch"},
+		key:        regexp.MustCompile(`^bulk_requests\.with_errors$`),
+		name:       "plugin_elasticsearch_bulk_requests_with_errors_total",
+		help:       "Number of bulk requests of an elasticsearch output with errors for some documents.",
+		valueType:  prometheus.CounterValue,
+	},
+	{
+		pluginType: "output",
+		plugins:    []string{"elasticsearch"},
+		key:        regexp.MustCompile(`^bulk_requests\.responses\.(\d+)$`),
+		name:       "plugin_elasticsearch_bulk_requests_responses_total",
+		help:       "Number of bulk requests of an elasticsearch output by HTTP response code.",
+		valueType:  prometheus.CounterValue,
+		labels:     []string{"code"},
+	},
+	{
+		pluginType: "output",
+		plugins:    []string{"elasticsearch"},
+		key:        regexp.MustCompile(`^documents\.successes$`),
+		name:       "plugin_elasticsearch_documents_successes_total",
+		help:       "Number of documents an elasticsearch output indexed successfully.",
+		valueType:  prometheus.CounterValue,
+	},
+	{
+		pluginType: "output",
+		plugins:    []string{"elasticsearch"},
+		key:        regexp.MustCompile(`^documents\.non_retryable_failures$`),
+		name:       "plugin_elasticsearch_documents_non_retryable_failures_total",
+		help:       "Number of documents an elasticsearch output failed to index and did not retry.",
+		valueType:  prometheus.CounterValue,
+	},
+	{
+		pluginType: "input",
+		plugins:    []string{"beats", "elastic_agent"},
+		key:        regexp.MustCompile(`^current_connections$`),
+		name:       "plugin_current_connections",
+		help:       "Number of open connections to a beats or elastic_agent input.",
+		valueType:  prometheus.GaugeValue,
+	},
+	{
+		pluginType: "input",
+		plugins:    []string{"beats", "elastic_agent"},
+		key:        regexp.MustCompile(`^peak_connections$`),
+		name:       "plugin_peak_connections",
+		help:       "Highest number of open connections to a beats or elastic_agent input.",
+		valueType:  prometheus.GaugeValue,
+	},
+	{
+		pluginType: "input",
+		plugins:    []string{"pipeline"},
+		key:        regexp.MustCompile(`^events\.out$`),
+		name:       "plugin_pipeline_events_received_total",
+		help:       "Number of events a pipeline input received from other pipelines.",
+		valueType:  prometheus.CounterValue,
+	},
+	{
+		pluginType: "output",
+		plugins:    []string{"pipeline"},
+		key:        regexp.MustCompile(`^events\.out$`),
+		name:       "plugin_pipeline_events_sent_total",
+		help:       "Number of events a pipeline output sent to other pipelines.",
+		valueType:  prometheus.CounterValue,
+	},
+}
+
+// newPluginMetricDescs returns the descs of specs, in the same order
+func newPluginMetricDescs(subsystem string, specs []pluginMetricSpec) []*prometheus.Desc {
+	descs := make([]*prometheus.Desc, len(specs))
+	for i, spec := range specs {
+		descs[i] = spec.def().desc(subsystem)
+	}
+	return descs
+}
+
+var (
+	keyGroupRegexp  = regexp.MustCompile(`\([^)]*\)`)
+	keyPatternChars = strings.NewReplacer("^", "", "$", "", `\.`, ".")
+)
+
+func (spec pluginMetricSpec) def() metricDef {
+	i := 0
+	key := keyGroupRegexp.ReplaceAllStringFunc(spec.key.String(), func(string) string {
+		i++
+		return "<" + spec.labels[i-1] + ">"
+	})
+
+	return metricDef{
+		name:      spec.name,
+		help:      spec.help,
+		valueType: spec.valueType,
+		labels:    append(append([]string{}, pluginScope.labels...), spec.labels...),
+		source:    strings.Replace(pluginScope.path, "<plugin_type>", spec.pluginType, 1) + "." + keyPatternChars.Replace(key),
+	}
+}
+
+// match returns the values of the spec's labels if it describes key of a
+// plugin of pluginType
+func (spec pluginMetricSpec) match(pluginType, plugin, key string) ([]string, bool) {
+	if spec.pluginType != pluginType {
+		return nil, false
+	}
+
+	known := false
+	for _, name := range spec.plugins {
+		known = known || name == plugin
+	}
+	if !known {
+		return nil, false
+	}
+
+	match := spec.key.FindStringSubmatch(key)
+	if match == nil {
+		return nil, false
+	}
+	return match[1:], true
+}
+
+// pluginStats returns the plugin-specific stats of plugin together with its
+// event counts, keyed as events.in and events.out
+func pluginStats(plugin Plugin) map[string]float64 {
+	stats := make(map[string]float64, len(plugin.Metrics)+2)
+	for key, value := range plugin.Metrics {
+		stats[key] = value
+	}
+	stats["events.in"] = float64(plugin.Events.In)
+	stats["events.out"] = float64(plugin.Events.Out)
+	return stats
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"fmt"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Config is the exporter configuration file
+type Config struct {
+	Targets []TargetConfig `yaml:"targets"`
+}
+
+// TargetConfig describes a Logstash node and how to scrape it
+type TargetConfig struct {
+	Name          string               `yaml:"name"`
+	URL           string               `yaml:"url"`
+	Timeout       time.Duration        `yaml:"timeout,omitempty"`
+	CacheTTL      *time.Duration       `yaml:"cache_ttl,omitempty"` // 0 disables caching
+	PollInterval  time.Duration        `yaml:"poll_interval,omitempty"`
+	BasicAuth     *BasicAuth           `yaml:"basic_auth,omitempty"`
+	Headers       map[string]Header    `yaml:"headers,omitempty"`
+	TLSConfig     *TLSConfig           `yaml:"tls_config,omitempty"`
+	Collectors    []string             `yaml:"collectors,omitempty"`
+	PluginMetrics *PluginMetricsConfig `yaml:"plugin_metrics,omitempty"`
+	HotThreads    int                  `yaml:"hot_threads,omitempty"`
+	Labels        map[string]string    `yaml:"labels,omitempty"`
+}
+
+// PluginMetricsConfig selects the plugin-specific metrics exported by key,
+// using regular expressions that must match the whole key
+type PluginMetricsConfig struct {
+	Allow []string `yaml:"allow,omitempty"`
+	Deny  []string `yaml:"deny,omitempty"`
+}
+
+// BasicAuth holds the credentials used towards the Logstash API
+type BasicAuth struct {
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password,omitempty"`
+	PasswordFile string `yaml:"password_file,omitempty"`
+}
+
+// Header is an HTTP header sent to the Logstash API, such as an API key,
+// given either inline or as a file
+type Header struct {
+	Value string `yaml:"value,omitempty"`
+	File  string `yaml:"file,omitempty"`
+}
+
+// LoadFile parses and validates the configuration file at filename
+func LoadFile(filename string) (*Config, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return Load(content)
+}
+
+// Load parses and validates a YAML configuration
+func Load(content []byte) (*Config, error) {
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict(content, cfg); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Target returns the target configured under name
+func (c *Config) Target(name string) (TargetConfig, bool) {
+	for _, target := range c.Targets {
+		if target.Name == name {
+			return target, true
+		}
+	}
+
+	return TargetConfig{}, false
+}
+
+func (c *Config) validate() error {
+	names := make(map[string]bool)
+	for i := range c.Targets {
+		target := &c.Targets[i]
+		if target.Name == "" {
+			return fmt.Errorf("target %d: name is missing", i)
+		}
+		if names[target.Name] {
+			return fmt.Errorf("target %q: duplicate name", target.Name)
+		}
+		names[target.Name] = true
+
+		if err := target.validate(); err != nil {
+			return fmt.Errorf("target %q: %v", target.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (t *TargetConfig) validate() error {
+	endpoint, err := ParseEndpoint(t.URL)
+	if err != nil {
+		return err
+	}
+	t.URL = endpoint
+
+	if t.Timeout < 0 {
+		return fmt.Errorf("negative timeout %s", t.Timeout)
+	}
+
+	if t.CacheTTL != nil && *t.CacheTTL < 0 {
+		return fmt.Errorf("negative cache_ttl %s", *t.CacheTTL)
+	}
+
+	if t.PollInterval < 0 {
+		return fmt.Errorf("negative poll_interval %s", t.PollInterval)
+	}
+
+	if t.BasicAuth != nil {
+		if t.BasicAuth.Username == "" {
+			return fmt.Errorf("basic_auth: username is missing")
+		}
+		if t.BasicAuth.Password != "" && t.BasicAuth.PasswordFile != "" {
+			return fmt.Errorf("basic_auth: at most one of password and password_file must be set")
+		}
+	}
+
+	for name, header := range t.Headers {
+		if name == "" || strings.ContainsAny(name, " :\t\r\n") {
+			return fmt.Errorf("headers: invalid header name %q", name)
+		}
+		if (header.Value == "") == (header.File == "") {
+			return fmt.Errorf("headers: exactly one of value and file must be set for %s", name)
+		}
+	}
+
+	if t.TLSConfig != nil {
+		if !strings.HasPrefix(t.URL, "https://") {
+			return fmt.Errorf("tls_config: requires an https url")
+		}
+		if err := t.TLSConfig.validate(); err != nil {
+			return err
+		}
+	}
+
+	if t.PluginMetrics != nil {
+		for _, expr := range append(append([]string{}, t.PluginMetrics.Allow...), t.PluginMetrics.Deny...) {
+			if _, err := regexp.Compile(expr); err != nil {
+				return fmt.Errorf("plugin_metrics: invalid regexp %q: %v", expr, err)
+			}
+		}
+	}
+
+	if t.HotThreads < 0 {
+		return fmt.Errorf("hot_threads: must not be negative")
+	}
+
+	for name := range t.Labels {
+		if !model.LabelName(name).IsValid() || strings.HasPrefix(name, model.ReservedLabelPrefix) {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+	}
+
+	return nil
+}
+
+// ParseEndpoint validates a Logstash API URL and normalizes it, defaulting to
+// http when no scheme is given
+func ParseEndpoint(endpoint string) (string, error) {
+	if endpoint == "" {
+		return "", fmt.Errorf("url is missing")
+	}
+
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid url %q: %v", endpoint, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid url %q: unsupported scheme %q", endpoint, u.Scheme)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("invalid url %q: missing host", endpoint)
+	}
+
+	return strings.TrimSuffix(u.String(), "/"), nil
+}
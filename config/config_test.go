@@ -0,0 +1,77 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	cfg, err := Load([]byte(`
+targets:
+  - name: logstash-1
+    url: logstash-1:9600/
+    timeout: 10s
+    cache_ttl: 0s
+    basic_auth:
+      username: exporter
+      password: secret
+    headers:
+      Authorization:
+        file: /etc/logstash_exporter/api_key
+    collectors: [node]
+    labels:
+      datacenter: eu-west-1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target, ok := cfg.Target("logstash-1")
+	if !ok {
+		t.Fatal("expected target logstash-1 to be configured")
+	}
+	if target.URL != "http://logstash-1:9600" {
+		t.Errorf("expected normalized url, got %q", target.URL)
+	}
+	if target.Timeout != 10*time.Second {
+		t.Errorf("expected timeout of 10s, got %s", target.Timeout)
+	}
+	if target.CacheTTL == nil || *target.CacheTTL != 0 {
+		t.Errorf("expected caching to be disabled explicitly, got %v", target.CacheTTL)
+	}
+	if target.BasicAuth == nil || target.BasicAuth.Password != "secret" {
+		t.Errorf("expected basic auth credentials, got %+v", target.BasicAuth)
+	}
+	if target.Headers["Authorization"].File != "/etc/logstash_exporter/api_key" {
+		t.Errorf("expected a header read from file, got %+v", target.Headers)
+	}
+	if target.Labels["datacenter"] != "eu-west-1" {
+		t.Errorf("expected extra labels, got %v", target.Labels)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := map[string]string{
+		"missing name":       "targets: [{url: 'http://logstash:9600'}]",
+		"duplicate name":     "targets: [{name: a, url: 'http://a:9600'}, {name: a, url: 'http://b:9600'}]",
+		"missing url":        "targets: [{name: a}]",
+		"bad scheme":         "targets: [{name: a, url: 'ftp://a:9600'}]",
+		"invalid label":      "targets: [{name: a, url: 'http://a:9600', labels: {'1x': y}}]",
+		"reserved label":     "targets: [{name: a, url: 'http://a:9600', labels: {__x: y}}]",
+		"unknown field":      "targets: [{name: a, url: 'http://a:9600', tiemout: 5s}]",
+		"missing username":   "targets: [{name: a, url: 'http://a:9600', basic_auth: {password: x}}]",
+		"negative timeout":   "targets: [{name: a, url: 'http://a:9600', timeout: -5s}]",
+		"two passwords":      "targets: [{name: a, url: 'http://a:9600', basic_auth: {username: u, password: x, password_file: f}}]",
+		"empty header":       "targets: [{name: a, url: 'http://a:9600', headers: {Authorization: {}}}]",
+		"invalid header":     "targets: [{name: a, url: 'http://a:9600', headers: {'X Key': {value: v}}}]",
+		"invalid regexp":     "targets: [{name: a, url: 'http://a:9600', plugin_metrics: {deny: ['(']}}]",
+		"negative threads":   "targets: [{name: a, url: 'http://a:9600', hot_threads: -1}]",
+		"malformed document": "targets: [",
+	}
+
+	for name, content := range tests {
+		if _, err := Load([]byte(content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
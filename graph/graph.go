@@ -0,0 +1,230 @@
+// Package graph renders Logstash pipeline graphs annotated with the current
+// stats of their plugins.
+package graph
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/BonnierNews/logstash_exporter/collector"
+	"strings"
+)
+
+// Graph is a pipeline graph whose plugin vertices carry their stats
+type Graph struct {
+	Pipeline string   `json:"pipeline"`
+	Vertices []Vertex `json:"vertices"`
+	Edges    []Edge   `json:"edges"`
+}
+
+// Vertex is a plugin, queue or conditional of a pipeline
+type Vertex struct {
+	ID         string       `json:"id"`
+	Type       string       `json:"type"`
+	PluginType string       `json:"plugin_type,omitempty"`
+	ConfigName string       `json:"config_name,omitempty"`
+	Condition  string       `json:"condition,omitempty"`
+	Stats      *PluginStats `json:"stats,omitempty"`
+}
+
+// PluginStats are the stats of a plugin at the time the graph was built.
+// Throughput, worker utilization and worker millis per event are the current
+// flow metrics of Logstash >=8.5, MillisPerEvent is the average over the
+// plugin's lifetime.
+type PluginStats struct {
+	EventsIn             int64    `json:"events_in"`
+	EventsOut            int64    `json:"events_out"`
+	DurationInMillis     int64    `json:"duration_in_millis"`
+	MillisPerEvent       *float64 `json:"millis_per_event,omitempty"`
+	Throughput           *float64 `json:"throughput,omitempty"`
+	WorkerUtilization    *float64 `json:"worker_utilization,omitempty"`
+	WorkerMillisPerEvent *float64 `json:"worker_millis_per_event,omitempty"`
+}
+
+// Edge connects two vertices. When is set on edges leaving a conditional.
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	When *bool  `json:"when,omitempty"`
+}
+
+// New annotates the plugin vertices of graph with the stats of pipeline
+func New(pipelineID string, graph collector.PipelineGraph, pipeline collector.Pipeline) *Graph {
+	stats := pluginStats(pipeline)
+
+	g := &Graph{
+		Pipeline: pipelineID,
+		Vertices: make([]Vertex, 0, len(graph.Vertices)),
+		Edges:    make([]Edge, 0, len(graph.Edges)),
+	}
+
+	for _, v := range graph.Vertices {
+		g.Vertices = append(g.Vertices, Vertex{
+			ID:         v.ID,
+			Type:       v.Type,
+			PluginType: v.PluginType,
+			ConfigName: v.ConfigName,
+			Condition:  v.Condition,
+			Stats:      stats[v.ID],
+		})
+	}
+
+	for _, e := range graph.Edges {
+		g.Edges = append(g.Edges, Edge{From: e.From, To: e.To, When: e.When})
+	}
+
+	return g
+}
+
+func pluginStats(pipeline collector.Pipeline) map[string]*PluginStats {
+	stats := make(map[string]*PluginStats)
+
+	for _, plugin := range pipeline.Plugins.Inputs {
+		stats[plugin.ID] = newPluginStats(int64(plugin.Events.Out), int64(plugin.Events.Out), 0, plugin.Flow)
+	}
+	for _, plugin := range pipeline.Plugins.Filters {
+		stats[plugin.ID] = newPluginStats(int64(plugin.Events.In), int64(plugin.Events.Out), int64(plugin.Events.DurationInMillis), plugin.Flow)
+	}
+	for _, plugin := range pipeline.Plugins.Outputs {
+		stats[plugin.ID] = newPluginStats(int64(plugin.Events.In), int64(plugin.Events.Out), int64(plugin.Events.DurationInMillis), plugin.Flow)
+	}
+
+	return stats
+}
+
+func newPluginStats(in, out, duration int64, flow collector.FlowMetrics) *PluginStats {
+	stats := &PluginStats{
+		EventsIn:             in,
+		EventsOut:            out,
+		DurationInMillis:     duration,
+		Throughput:           currentFlow(flow, "throughput"),
+		WorkerUtilization:    currentFlow(flow, "worker_utilization"),
+		WorkerMillisPerEvent: currentFlow(flow, "worker_millis_per_event"),
+	}
+
+	if duration > 0 && in > 0 {
+		millisPerEvent := float64(duration) / float64(in)
+		stats.MillisPerEvent = &millisPerEvent
+	}
+
+	return stats
+}
+
+func currentFlow(flow collector.FlowMetrics, metric string) *float64 {
+	value, ok := flow[metric]["current"]
+	if !ok {
+		return nil
+	}
+	return &value
+}
+
+// label describes a vertex in a single line, e.g.
+// "filter grok (in 100, out 98, 0.50 ms/event)"
+func (v Vertex) label() string {
+	var name string
+	switch v.Type {
+	case "plugin":
+		name = v.PluginType + " " + v.ConfigName
+	case "if":
+		name = "if " + v.Condition
+	default:
+		name = v.Type
+	}
+
+	if v.Stats == nil {
+		return name
+	}
+
+	details := []string{
+		fmt.Sprintf("in %d", v.Stats.EventsIn),
+		fmt.Sprintf("out %d", v.Stats.EventsOut),
+	}
+	if v.Stats.Throughput != nil {
+		details = append(details, fmt.Sprintf("%.2f events/s", *v.Stats.Throughput))
+	}
+	if v.Stats.WorkerMillisPerEvent != nil {
+		details = append(details, fmt.Sprintf("%.2f ms/event", *v.Stats.WorkerMillisPerEvent))
+	} else if v.Stats.MillisPerEvent != nil {
+		details = append(details, fmt.Sprintf("%.2f ms/event", *v.Stats.MillisPerEvent))
+	}
+	if v.Stats.WorkerUtilization != nil {
+		details = append(details, fmt.Sprintf("%.1f%% utilization", *v.Stats.WorkerUtilization))
+	}
+
+	return fmt.Sprintf("%s (%s)", name, strings.Join(details, ", "))
+}
+
+// label describes an edge, which is only labeled when leaving a conditional
+func (e Edge) label() string {
+	if e.When == nil {
+		return ""
+	}
+	return fmt.Sprint(*e.When)
+}
+
+// DOT renders the graph in the Graphviz DOT language
+func (g *Graph) DOT() string {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "digraph %q {\n", g.Pipeline)
+	b.WriteString("  rankdir=LR;\n")
+	for _, v := range g.Vertices {
+		shape := "box"
+		switch v.Type {
+		case "if":
+			shape = "diamond"
+		case "queue":
+			shape = "cylinder"
+		}
+		fmt.Fprintf(&b, "  %q [label=%q, shape=%s];\n", v.ID, v.label(), shape)
+	}
+	for _, e := range g.Edges {
+		if label := e.label(); label != "" {
+			fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", e.From, e.To, label)
+		} else {
+			fmt.Fprintf(&b, "  %q -> %q;\n", e.From, e.To)
+		}
+	}
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart
+func (g *Graph) Mermaid() string {
+	var b bytes.Buffer
+
+	ids := make(map[string]string, len(g.Vertices))
+	id := func(vertex string) string {
+		if _, ok := ids[vertex]; !ok {
+			ids[vertex] = fmt.Sprintf("v%d", len(ids))
+		}
+		return ids[vertex]
+	}
+
+	b.WriteString("flowchart LR\n")
+	for _, v := range g.Vertices {
+		start, end := "[", "]"
+		switch v.Type {
+		case "if":
+			start, end = "{", "}"
+		case "queue":
+			start, end = "[(", ")]"
+		}
+		fmt.Fprintf(&b, "  %s%s\"%s\"%s\n", id(v.ID), start, mermaidEscape(v.label()), end)
+	}
+	for _, e := range g.Edges {
+		if label := e.label(); label != "" {
+			fmt.Fprintf(&b, "  %s -->|%s| %s\n", id(e.From), label, id(e.To))
+		} else {
+			fmt.Fprintf(&b, "  %s --> %s\n", id(e.From), id(e.To))
+		}
+	}
+
+	return b.String()
+}
+
+// mermaidEscape replaces the characters that cannot appear in a quoted
+// Mermaid label by their entity codes
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(s)
+}
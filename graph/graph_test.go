@@ -0,0 +1,103 @@
+package graph
+
+import (
+	"encoding/json"
+	"github.com/BonnierNews/logstash_exporter/collector"
+	"strings"
+	"testing"
+)
+
+var graphJSON = []byte(`
+{
+  "vertices": [
+    {"id": "beats-in", "explicit_id": true, "type": "plugin", "plugin_type": "input", "config_name": "beats"},
+    {"id": "__QUEUE__", "explicit_id": false, "type": "queue"},
+    {"id": "cond-1", "explicit_id": false, "type": "if", "condition": "[type] == \"syslog\""},
+    {"id": "grok-1", "explicit_id": true, "type": "plugin", "plugin_type": "filter", "config_name": "grok"},
+    {"id": "es-out", "explicit_id": true, "type": "plugin", "plugin_type": "output", "config_name": "elasticsearch"}
+  ],
+  "edges": [
+    {"id": "e1", "from": "beats-in", "to": "__QUEUE__", "type": "plain"},
+    {"id": "e2", "from": "__QUEUE__", "to": "cond-1", "type": "plain"},
+    {"id": "e3", "from": "cond-1", "to": "grok-1", "type": "boolean", "when": true},
+    {"id": "e4", "from": "cond-1", "to": "es-out", "type": "boolean", "when": false},
+    {"id": "e5", "from": "grok-1", "to": "es-out", "type": "plain"}
+  ]
+}
+`)
+
+var pipelineJSON = []byte(`
+{
+  "plugins": {
+    "inputs": [{"id": "beats-in", "name": "beats", "events": {"out": 200}, "flow": {"throughput": {"current": 12.5}}}],
+    "filters": [{"id": "grok-1", "name": "grok", "events": {"in": 100, "out": 98, "duration_in_millis": 50}}],
+    "outputs": [{"id": "es-out", "name": "elasticsearch", "events": {"in": 200, "out": 200, "duration_in_millis": 1000}}]
+  }
+}
+`)
+
+func testGraph(t *testing.T) *Graph {
+	var (
+		pipelineGraph collector.PipelineGraph
+		pipeline      collector.Pipeline
+	)
+	if err := json.Unmarshal(graphJSON, &pipelineGraph); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(pipelineJSON, &pipeline); err != nil {
+		t.Fatal(err)
+	}
+	return New("main", pipelineGraph, pipeline)
+}
+
+func TestNew(t *testing.T) {
+	g := testGraph(t)
+
+	if len(g.Vertices) != 5 || len(g.Edges) != 5 {
+		t.Fatalf("expected 5 vertices and 5 edges, got %d and %d", len(g.Vertices), len(g.Edges))
+	}
+
+	stats := g.Vertices[3].Stats
+	if stats == nil || stats.EventsIn != 100 || stats.EventsOut != 98 || *stats.MillisPerEvent != 0.5 {
+		t.Errorf("expected grok stats, got %+v", stats)
+	}
+	if stats := g.Vertices[0].Stats; stats == nil || *stats.Throughput != 12.5 {
+		t.Errorf("expected beats throughput, got %+v", stats)
+	}
+	if g.Vertices[1].Stats != nil {
+		t.Errorf("expected no stats for the queue, got %+v", g.Vertices[1].Stats)
+	}
+}
+
+func TestDOT(t *testing.T) {
+	dot := testGraph(t).DOT()
+
+	for _, expected := range []string{
+		`digraph "main" {`,
+		`"grok-1" [label="filter grok (in 100, out 98, 0.50 ms/event)", shape=box];`,
+		`"cond-1" [label="if [type] == \"syslog\"", shape=diamond];`,
+		`"cond-1" -> "es-out" [label="false"];`,
+		`"grok-1" -> "es-out";`,
+	} {
+		if !strings.Contains(dot, expected) {
+			t.Errorf("expected %s in:\n%s", expected, dot)
+		}
+	}
+}
+
+func TestMermaid(t *testing.T) {
+	mermaid := testGraph(t).Mermaid()
+
+	for _, expected := range []string{
+		"flowchart LR\n",
+		`v0["input beats (in 200, out 200, 12.50 events/s)"]`,
+		`v1[("queue")]`,
+		`v2{"if [type] == #quot;syslog#quot;"}`,
+		"v2 -->|true| v3",
+		"v3 --> v4",
+	} {
+		if !strings.Contains(mermaid, expected) {
+			t.Errorf("expected %s in:\n%s", expected, mermaid)
+		}
+	}
+}
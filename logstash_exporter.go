@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"github.com/BonnierNews/logstash_exporter/collector"
+	"github.com/BonnierNews/logstash_exporter/config"
+	"github.com/BonnierNews/logstash_exporter/web"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/common/log"
 	"github.com/prometheus/common/version"
 	"gopkg.in/alecthomas/kingpin.v2"
 	"net/http"
-	_ "net/http/pprof"
+	"net/http/pprof"
+	"os"
+	"os/signal"
+	"strconv"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -22,71 +31,328 @@ var (
 		},
 		[]string{"collector", "result"},
 	)
+
+	upDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(collector.Namespace, "", "up"),
+		"Whether all collectors succeeded in scraping Logstash.",
+		nil,
+		nil,
+	)
+
+	certificateExpiryDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(collector.Namespace, "api", "certificate_expiry_timestamp_seconds"),
+		"Unix timestamp when the earliest expiring certificate presented by the Logstash API expires.",
+		nil,
+		nil,
+	)
+
+	snapshotAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(collector.Namespace, "exporter", "snapshot_age_seconds"),
+		"logstash_exporter: Age of the node stats snapshot served for a target polled in the background.",
+		nil,
+		nil,
+	)
+
+	pollFailuresDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(collector.Namespace, "exporter", "poll_consecutive_failures"),
+		"logstash_exporter: Number of consecutive failed polls of a target polled in the background.",
+		nil,
+		nil,
+	)
+
+	collectorUpDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(collector.Namespace, "exporter", "collector_up"),
+		"Whether a collector succeeded in scraping Logstash.",
+		[]string{"collector"},
+		nil,
+	)
 )
 
 // LogstashCollector collector type
 type LogstashCollector struct {
+	// ctx bounds a single scrape, LogstashCollector is built per request.
+	ctx        context.Context
+	target     *collector.Target
 	collectors map[string]collector.Collector
 }
 
-// NewLogstashCollector register a logstash collector
-func NewLogstashCollector(logstashEndpoint string) (*LogstashCollector, error) {
-	nodeStatsCollector, err := collector.NewNodeStatsCollector(logstashEndpoint)
-	if err != nil {
-		log.Fatalf("Cannot register a new collector: %v", err)
+var (
+	collectorFactories = map[string]func(*collector.Target) (collector.Collector, error){
+		"node":        collector.NewNodeStatsCollector,
+		"info":        collector.NewNodeInfoCollector,
+		"hot_threads": collector.NewHotThreadsCollector,
+		"plugins":     collector.NewPluginsCollector,
+		"pipelines":   collector.NewNodePipelinesCollector,
+		"health":      collector.NewHealthReportCollector,
+	}
+
+	defaultCollectors = []string{"node", "info"}
+
+	defaultScrapeTimeout time.Duration
+	scrapeTimeoutOffset  time.Duration
+	defaultCacheTTL      time.Duration
+	pollInterval         time.Duration
+	endpointCollectors   []string
+	defaultHotThreads    int
+
+	pluginMetricsAllow      []string
+	pluginMetricsDeny       []string
+	defaultPluginMetricKeys *collector.KeyFilter
+)
+
+// NewLogstashCollector register a logstash collector running the enabled
+// collectors, or the default ones if none are given, against target until ctx
+// is done
+func NewLogstashCollector(ctx context.Context, target *collector.Target, enabledCollectors []string) (*LogstashCollector, error) {
+	if len(enabledCollectors) == 0 {
+		enabledCollectors = defaultCollectors
 	}
 
-	nodeInfoCollector, err := collector.NewNodeInfoCollector(logstashEndpoint)
-	if err != nil {
-		log.Fatalf("Cannot register a new collector: %v", err)
+	collectors := make(map[string]collector.Collector, len(enabledCollectors))
+	for _, name := range enabledCollectors {
+		factory, ok := collectorFactories[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown collector %q", name)
+		}
+
+		c, err := factory(target)
+		if err != nil {
+			return nil, fmt.Errorf("cannot register a new collector: %v", err)
+		}
+		collectors[name] = c
 	}
 
 	return &LogstashCollector{
-		collectors: map[string]collector.Collector{
-			"node": nodeStatsCollector,
-			"info": nodeInfoCollector,
-		},
+		ctx:        ctx,
+		target:     target,
+		collectors: collectors,
 	}, nil
 }
 
-func listen(exporterBindAddress string) {
-	http.Handle("/metrics", prometheus.Handler())
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+// newTarget builds the collector.Target for a configured Logstash node
+func newTarget(cfg config.TargetConfig) (*collector.Target, error) {
+	target := collector.NewTarget(cfg.URL)
+	target.CacheTTL = defaultCacheTTL
+	if cfg.CacheTTL != nil {
+		target.CacheTTL = *cfg.CacheTTL
+	}
+
+	if cfg.PollInterval > 0 {
+		timeout := cfg.Timeout
+		if timeout == 0 {
+			timeout = defaultScrapeTimeout
+		}
+		target.Poller = collector.NewPoller(target, cfg.PollInterval, timeout)
+	}
+
+	target.PluginMetricKeys = defaultPluginMetricKeys
+	if cfg.PluginMetrics != nil {
+		keys, err := collector.NewKeyFilter(cfg.PluginMetrics.Allow, cfg.PluginMetrics.Deny)
+		if err != nil {
+			return nil, err
+		}
+		target.PluginMetricKeys = keys
+	}
+
+	target.HotThreads = defaultHotThreads
+	if cfg.HotThreads > 0 {
+		target.HotThreads = cfg.HotThreads
+	}
+
+	if cfg.TLSConfig != nil {
+		tlsConfig, err := config.NewTLSConfig(cfg.TLSConfig)
+		if err != nil {
+			return nil, err
+		}
+		target.Client = collector.NewTLSClient(tlsConfig)
+	}
+
+	if cfg.BasicAuth != nil {
+		target.BasicAuth = &collector.BasicAuth{
+			Username: cfg.BasicAuth.Username,
+			Password: collector.NewSecret(cfg.BasicAuth.Password, cfg.BasicAuth.PasswordFile),
+		}
+	}
+
+	if len(cfg.Headers) > 0 {
+		target.Headers = make(map[string]*collector.Secret, len(cfg.Headers))
+		for name, header := range cfg.Headers {
+			target.Headers[name] = collector.NewSecret(header.Value, header.File)
+		}
+	}
+
+	return target, nil
+}
+
+// scrapeContext bounds a scrape of Logstash by Prometheus' scrape timeout
+// minus scrapeTimeoutOffset, or by timeout if that is shorter or Prometheus
+// did not send its scrape timeout
+func scrapeContext(r *http.Request, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if v := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"); v != "" {
+		seconds, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			log.Warnf("Cannot parse X-Prometheus-Scrape-Timeout-Seconds header %q: %v", v, err)
+		} else if prometheusTimeout := time.Duration(seconds*float64(time.Second)) - scrapeTimeoutOffset; prometheusTimeout > 0 && (timeout <= 0 || prometheusTimeout < timeout) {
+			timeout = prometheusTimeout
+		}
+	}
+
+	if timeout <= 0 {
+		return context.WithCancel(r.Context())
+	}
+	return context.WithTimeout(r.Context(), timeout)
+}
+
+// endpointTarget returns the long-lived target for logstashEndpoint, polled
+// in the background if -logstash.poll-interval is set
+func endpointTarget(logstashEndpoint string) *collector.Target {
+	target := collector.NewTarget(logstashEndpoint)
+	target.CacheTTL = defaultCacheTTL
+	target.PluginMetricKeys = defaultPluginMetricKeys
+	target.HotThreads = defaultHotThreads
+
+	if pollInterval > 0 {
+		target.Poller = collector.NewPoller(target, pollInterval, defaultScrapeTimeout)
+		target.Poller.Start()
+	}
+
+	return target
+}
+
+// metricsHandler serves exporter self-metrics, together with a scrape of the
+// Logstash node at target unless it is nil
+func metricsHandler(target *collector.Target) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		gatherers := prometheus.Gatherers{prometheus.DefaultGatherer}
+
+		if target != nil {
+			ctx, cancel := scrapeContext(r, defaultScrapeTimeout)
+			defer cancel()
+
+			logstashCollector, err := NewLogstashCollector(ctx, target, endpointCollectors)
+			if err != nil {
+				log.Errorf("Cannot create a Logstash Collector for %s: %v", target.Endpoint, err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			registry := prometheus.NewRegistry()
+			registry.MustRegister(logstashCollector)
+			gatherers = append(gatherers, registry)
+		}
+
+		promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	}
+}
+
+func listen(exporterBindAddress, adminBindAddress, webConfigFile, logstashEndpoint string) {
+	var target *collector.Target
+	if logstashEndpoint != "" {
+		target = endpointTarget(logstashEndpoint)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metricsHandler(target))
+	mux.HandleFunc("/probe", probeHandler)
+	mux.Handle("/pipelines/", graphHandler(target))
+	mux.HandleFunc("/-/reload", reloadHandler)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "/metrics", http.StatusMovedPermanently)
 	})
 
+	if adminBindAddress != "" {
+		go listenAdmin(adminBindAddress, webConfigFile)
+	}
+
 	log.Infoln("Starting server on", exporterBindAddress)
-	if err := http.ListenAndServe(exporterBindAddress, nil); err != nil {
+	server := &http.Server{Addr: exporterBindAddress, Handler: mux}
+	if err := web.ListenAndServe(server, webConfigFile); err != nil {
 		log.Fatalf("Cannot start Logstash exporter: %s", err)
 	}
 }
 
+// listenAdmin serves the pprof debugging endpoints on their own listener
+func listenAdmin(adminBindAddress, webConfigFile string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	log.Infoln("Starting admin server on", adminBindAddress)
+	server := &http.Server{Addr: adminBindAddress, Handler: mux}
+	if err := web.ListenAndServe(server, webConfigFile); err != nil {
+		log.Fatalf("Cannot start admin server: %s", err)
+	}
+}
+
+// describer is implemented by collectors that describe their metrics up front
+type describer interface {
+	Describe(ch chan<- *prometheus.Desc)
+}
+
 // Describe logstash metrics
 func (coll LogstashCollector) Describe(ch chan<- *prometheus.Desc) {
-	scrapeDurations.Describe(ch)
+	ch <- upDesc
+	ch <- collectorUpDesc
+	ch <- certificateExpiryDesc
+	ch <- snapshotAgeDesc
+	ch <- pollFailuresDesc
+
+	for _, c := range coll.collectors {
+		if d, ok := c.(describer); ok {
+			d.Describe(ch)
+		}
+	}
 }
 
 // Collect logstash metrics
 func (coll LogstashCollector) Collect(ch chan<- prometheus.Metric) {
-	wg := sync.WaitGroup{}
+	var (
+		wg  = sync.WaitGroup{}
+		mtx = sync.Mutex{}
+		up  = 1.0
+	)
 	wg.Add(len(coll.collectors))
 	for name, c := range coll.collectors {
 		go func(name string, c collector.Collector) {
-			execute(name, c, ch)
-			wg.Done()
+			defer wg.Done()
+			collectorUp := 1.0
+			if err := execute(coll.ctx, name, c, ch); err != nil {
+				collectorUp = 0
+				mtx.Lock()
+				up = 0
+				mtx.Unlock()
+			}
+			ch <- prometheus.MustNewConstMetric(collectorUpDesc, prometheus.GaugeValue, collectorUp, name)
 		}(name, c)
 	}
 	wg.Wait()
-	scrapeDurations.Collect(ch)
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, up)
+
+	if expiry := coll.target.CertificateExpiry(); !expiry.IsZero() {
+		ch <- prometheus.MustNewConstMetric(certificateExpiryDesc, prometheus.GaugeValue, float64(expiry.Unix()))
+	}
+
+	if poller := coll.target.Poller; poller != nil {
+		if _, updated, err := poller.Snapshot(); err == nil {
+			ch <- prometheus.MustNewConstMetric(snapshotAgeDesc, prometheus.GaugeValue, time.Since(updated).Seconds())
+		}
+		ch <- prometheus.MustNewConstMetric(pollFailuresDesc, prometheus.GaugeValue, float64(poller.ConsecutiveFailures()))
+	}
 }
 
-func execute(name string, c collector.Collector, ch chan<- prometheus.Metric) {
+func execute(ctx context.Context, name string, c collector.Collector, ch chan<- prometheus.Metric) error {
 	begin := time.Now()
-	err := c.Collect(ch)
+	err := safeCollect(ctx, c, ch)
 	duration := time.Since(begin)
 	var result string
 
-	if err != nil {
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		log.Errorf("ERROR: %s collector timed out after %fs: %s", name, duration.Seconds(), err)
+		result = "timeout"
+	} else if err != nil {
 		log.Errorf("ERROR: %s collector failed after %fs: %s", name, duration.Seconds(), err)
 		result = "error"
 	} else {
@@ -94,31 +360,81 @@ func execute(name string, c collector.Collector, ch chan<- prometheus.Metric) {
 		result = "success"
 	}
 	scrapeDurations.WithLabelValues(name, result).Observe(duration.Seconds())
+	return err
+}
+
+// safeCollect turns panics raised while collecting, such as label mismatches
+// in prometheus.MustNewConstMetric, into errors instead of crashing the scrape.
+func safeCollect(ctx context.Context, c collector.Collector, ch chan<- prometheus.Metric) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic while collecting: %v", r)
+		}
+	}()
+	return c.Collect(ctx, ch)
 }
 
 func init() {
 	prometheus.MustRegister(version.NewCollector("logstash_exporter"))
+	prometheus.MustRegister(scrapeDurations)
+	prometheus.MustRegister(collector.CacheHits)
+	prometheus.MustRegister(collector.CacheMisses)
+	prometheus.MustRegister(configReloadSuccess)
+	prometheus.MustRegister(configReloadSeconds)
 }
 
 func main() {
 	var (
-		logstashEndpoint    = kingpin.Flag("logstash.endpoint", "The protocol, host and port on which logstash metrics API listens").Default("http://localhost:9600").String()
+		logstashEndpoint    = kingpin.Flag("logstash.endpoint", "The protocol, host and port on which logstash metrics API listens, scraped on /metrics. Set to an empty string to only serve exporter metrics and /probe.").Default("http://localhost:9600").String()
 		exporterBindAddress = kingpin.Flag("web.listen-address", "Address on which to expose metrics and web interface.").Default(":9198").String()
+		configFile          = kingpin.Flag("config.file", "Path to a YAML configuration file describing Logstash targets for /probe.").Default("").String()
+		webConfigFile       = kingpin.Flag("web.config.file", "Path to a web configuration file enabling TLS and basic authentication on all listeners.").Default("").String()
+		adminBindAddress    = kingpin.Flag("web.admin-listen-address", "Address on which to expose pprof debugging endpoints. Disabled if empty.").Default("").String()
 	)
 
+	kingpin.Flag("logstash.timeout", "Timeout for scraping Logstash when Prometheus does not send a shorter one, unless configured per target.").Default("10s").DurationVar(&defaultScrapeTimeout)
+	kingpin.Flag("logstash.cache-ttl", "How long Logstash API responses are reused across scrapes, unless configured per target. Disabled if 0.").Default("0s").DurationVar(&defaultCacheTTL)
+	kingpin.Flag("logstash.poll-interval", "Poll node stats of --logstash.endpoint in the background on this interval and serve scrapes from the last snapshot. Disabled if 0.").Default("0s").DurationVar(&pollInterval)
+	kingpin.Flag("logstash.collector", "Collector to run against --logstash.endpoint, repeat to enable several. Defaults to all collectors enabled by default.").StringsVar(&endpointCollectors)
+	kingpin.Flag("logstash.plugin-metrics.allow", "Regular expression matching the keys of plugin-specific metrics to export, repeat to allow several. All are exported if not set, unless configured per target.").StringsVar(&pluginMetricsAllow)
+	kingpin.Flag("logstash.plugin-metrics.deny", "Regular expression matching the keys of plugin-specific metrics not to export, repeat to deny several, unless configured per target.").StringsVar(&pluginMetricsDeny)
+	kingpin.Flag("logstash.hot-threads", "Number of the busiest threads reported by the hot_threads collector, unless configured per target.").Default("10").IntVar(&defaultHotThreads)
+	kingpin.Flag("web.timeout-offset", "Offset to subtract from Prometheus' scrape timeout.").Default("0.5s").DurationVar(&scrapeTimeoutOffset)
+
 	log.AddFlags(kingpin.CommandLine)
 	kingpin.Version(version.Print("logstash_exporter"))
 	kingpin.HelpFlag.Short('h')
 	kingpin.Parse()
 
-	logstashCollector, err := NewLogstashCollector(*logstashEndpoint)
-	if err != nil {
-		log.Fatalf("Cannot register a new Logstash Collector: %v", err)
+	for _, name := range endpointCollectors {
+		if _, ok := collectorFactories[name]; !ok {
+			log.Fatalf("Unknown collector %q", name)
+		}
+	}
+
+	var err error
+	if defaultPluginMetricKeys, err = collector.NewKeyFilter(pluginMetricsAllow, pluginMetricsDeny); err != nil {
+		log.Fatalf("Invalid plugin metrics flags: %v", err)
+	}
+
+	exporterConfig.filename = *configFile
+	if err := exporterConfig.reload(); err != nil {
+		log.Fatalf("Error loading config: %v", err)
 	}
 
-	prometheus.MustRegister(logstashCollector)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := exporterConfig.reload(); err != nil {
+				log.Errorf("Error reloading config: %v", err)
+				continue
+			}
+			log.Infoln("Reloaded config file", *configFile)
+		}
+	}()
 
 	log.Infoln("Starting Logstash exporter", version.Info())
 	log.Infoln("Build context", version.BuildContext())
-	listen(*exporterBindAddress)
+	listen(*exporterBindAddress, *adminBindAddress, *webConfigFile, *logstashEndpoint)
 }
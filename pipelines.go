@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/BonnierNews/logstash_exporter/collector"
+	"github.com/BonnierNews/logstash_exporter/graph"
+	"github.com/prometheus/common/log"
+	"net/http"
+	"strings"
+)
+
+// graphHandler serves /pipelines/<id>/graph, the graph of a pipeline with its
+// plugins annotated with their current stats, as DOT, Mermaid or JSON
+// depending on the format query parameter. The pipeline runs on the node at
+// target, or the one given by the target query parameter as for /probe.
+func graphHandler(endpointTarget *collector.Target) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pipelineID, ok := graphPipelineID(r.URL.Path)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		target, timeout := endpointTarget, defaultScrapeTimeout
+		if name := r.URL.Query().Get("target"); name != "" {
+			targetConfig, t, err := probeTarget(name)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if targetConfig.Timeout != 0 {
+				timeout = targetConfig.Timeout
+			}
+			target = t
+		} else if target == nil {
+			http.Error(w, "target parameter is missing", http.StatusBadRequest)
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "json"
+		}
+		if format != "json" && format != "dot" && format != "mermaid" {
+			http.Error(w, fmt.Sprintf("unknown format %q, expected json, dot or mermaid", format), http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := scrapeContext(r, timeout)
+		defer cancel()
+
+		pipelineGraph, err := collector.NodePipelineGraph(ctx, target, pipelineID)
+		if err != nil {
+			log.Errorf("Cannot retrieve graph of pipeline %q from %s: %v", pipelineID, target.Endpoint, err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		stats, err := collector.LatestNodeStats(ctx, target)
+		if err != nil {
+			log.Warnf("Cannot retrieve stats of pipeline %q from %s, serving its graph without them: %v", pipelineID, target.Endpoint, err)
+		}
+
+		g := graph.New(pipelineID, pipelineGraph, stats.Pipelines[pipelineID])
+
+		switch format {
+		case "dot":
+			w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+			fmt.Fprint(w, g.DOT())
+		case "mermaid":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprint(w, g.Mermaid())
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(g); err != nil {
+				log.Errorf("Cannot write graph of pipeline %q: %v", pipelineID, err)
+			}
+		}
+	}
+}
+
+// graphPipelineID extracts the pipeline ID from a /pipelines/<id>/graph path
+func graphPipelineID(path string) (string, bool) {
+	if !strings.HasPrefix(path, "/pipelines/") || !strings.HasSuffix(path, "/graph") {
+		return "", false
+	}
+
+	pipelineID := strings.TrimSuffix(strings.TrimPrefix(path, "/pipelines/"), "/graph")
+	if pipelineID == "" || strings.Contains(pipelineID, "/") {
+		return "", false
+	}
+
+	return pipelineID, true
+}
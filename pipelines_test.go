@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGraphPipelineID(t *testing.T) {
+	tests := []struct {
+		path       string
+		pipelineID string
+		valid      bool
+	}{
+		{"/pipelines/main/graph", "main", true},
+		{"/pipelines/my-pipeline/graph", "my-pipeline", true},
+		{"/pipelines//graph", "", false},
+		{"/pipelines/main", "", false},
+		{"/pipelines/a/b/graph", "", false},
+	}
+
+	for _, test := range tests {
+		pipelineID, ok := graphPipelineID(test.path)
+		if ok != test.valid || pipelineID != test.pipelineID {
+			t.Errorf("%q: expected %q, %v, got %q, %v", test.path, test.pipelineID, test.valid, pipelineID, ok)
+		}
+	}
+}
+
+func TestGraphHandler(t *testing.T) {
+	logstash := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_node/pipelines/main":
+			if r.URL.Query().Get("graph") != "true" {
+				t.Errorf("expected the graph to be requested, got %s", r.URL)
+			}
+			w.Write([]byte(`{"pipelines": {"main": {"graph": {"graph": {
+				"vertices": [
+					{"id": "in", "type": "plugin", "plugin_type": "input", "config_name": "generator"},
+					{"id": "out", "type": "plugin", "plugin_type": "output", "config_name": "stdout"}
+				],
+				"edges": [{"id": "e", "from": "in", "to": "out", "type": "plain"}]
+			}}}}}`))
+		case "/_node/stats":
+			w.Write([]byte(`{"pipelines": {"main": {"plugins": {"outputs": [
+				{"id": "out", "name": "stdout", "events": {"in": 10, "out": 10, "duration_in_millis": 20}}
+			]}}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer logstash.Close()
+
+	tests := []struct {
+		query       string
+		status      int
+		contentType string
+		contains    string
+	}{
+		{"", http.StatusOK, "application/json", `"config_name":"stdout","stats":{"events_in":10,"events_out":10,"duration_in_millis":20,"millis_per_event":2}`},
+		{"format=dot", http.StatusOK, "text/vnd.graphviz; charset=utf-8", `"out" [label="output stdout (in 10, out 10, 2.00 ms/event)", shape=box];`},
+		{"format=mermaid", http.StatusOK, "text/plain; charset=utf-8", "v0 --> v1"},
+		{"format=svg", http.StatusBadRequest, "", ""},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/pipelines/main/graph?target="+logstash.URL+"&"+test.query, nil)
+		rec := httptest.NewRecorder()
+		graphHandler(nil).ServeHTTP(rec, req)
+
+		if rec.Code != test.status {
+			t.Errorf("%q: expected status %d, got %d", test.query, test.status, rec.Code)
+			continue
+		}
+		if test.status != http.StatusOK {
+			continue
+		}
+		if contentType := rec.Header().Get("Content-Type"); contentType != test.contentType {
+			t.Errorf("%q: expected content type %q, got %q", test.query, test.contentType, contentType)
+		}
+		body, _ := ioutil.ReadAll(rec.Body)
+		if !strings.Contains(string(body), test.contains) {
+			t.Errorf("%q: expected %s in:\n%s", test.query, test.contains, body)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"github.com/BonnierNews/logstash_exporter/collector"
+	"github.com/BonnierNews/logstash_exporter/config"
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/log"
+	"net/http"
+	"sort"
+)
+
+// probeHandler scrapes the Logstash node given by the target query parameter
+// using a fresh LogstashCollector registered on its own registry, in the style
+// of the blackbox exporter. The target is either the name of a target in the
+// configuration file or the URL of a Logstash API.
+func probeHandler(w http.ResponseWriter, r *http.Request) {
+	targetConfig, target, err := probeTarget(r.URL.Query().Get("target"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	timeout := targetConfig.Timeout
+	if timeout == 0 {
+		timeout = defaultScrapeTimeout
+	}
+	ctx, cancel := scrapeContext(r, timeout)
+	defer cancel()
+
+	logstashCollector, err := NewLogstashCollector(ctx, target, targetConfig.Collectors)
+	if err != nil {
+		log.Errorf("Cannot create a Logstash Collector for %s: %v", targetConfig.URL, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(logstashCollector)
+
+	var gatherer prometheus.Gatherer = registry
+	if len(targetConfig.Labels) > 0 {
+		gatherer = labelGatherer{gatherer: registry, labels: targetConfig.Labels}
+	}
+
+	promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// probeTarget looks up target in the configuration, falling back to treating
+// it as the URL of a Logstash API with default settings
+func probeTarget(target string) (config.TargetConfig, *collector.Target, error) {
+	if targetConfig, t, ok := exporterConfig.target(target); ok {
+		return targetConfig, t, nil
+	}
+
+	endpoint, err := probeEndpoint(target)
+	if err != nil {
+		return config.TargetConfig{}, nil, err
+	}
+
+	targetConfig := config.TargetConfig{URL: endpoint}
+	t, err := exporterConfig.urlTarget(targetConfig)
+	return targetConfig, t, err
+}
+
+// probeEndpoint validates a probe target and turns it into a Logstash endpoint,
+// defaulting to http when no scheme is given.
+func probeEndpoint(target string) (string, error) {
+	if target == "" {
+		return "", fmt.Errorf("target parameter is missing")
+	}
+
+	endpoint, err := config.ParseEndpoint(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid target: %v", err)
+	}
+
+	return endpoint, nil
+}
+
+// labelGatherer adds a target's extra labels to every metric it gathers.
+// Labels already present on a metric are left untouched.
+type labelGatherer struct {
+	gatherer prometheus.Gatherer
+	labels   map[string]string
+}
+
+// Gather implements prometheus.Gatherer
+func (g labelGatherer) Gather() ([]*dto.MetricFamily, error) {
+	mfs, err := g.gatherer.Gather()
+
+	for _, mf := range mfs {
+		for _, m := range mf.Metric {
+			present := make(map[string]bool, len(m.Label))
+			for _, lp := range m.Label {
+				present[lp.GetName()] = true
+			}
+
+			for name, value := range g.labels {
+				if !present[name] {
+					m.Label = append(m.Label, &dto.LabelPair{
+						Name:  proto.String(name),
+						Value: proto.String(value),
+					})
+				}
+			}
+
+			sort.Slice(m.Label, func(i, j int) bool {
+				return m.Label[i].GetName() < m.Label[j].GetName()
+			})
+		}
+	}
+
+	return mfs, err
+}
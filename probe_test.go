@@ -0,0 +1,148 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProbeEndpoint(t *testing.T) {
+	tests := []struct {
+		target   string
+		endpoint string
+		valid    bool
+	}{
+		{"http://logstash:9600", "http://logstash:9600", true},
+		{"https://logstash:9600/", "https://logstash:9600", true},
+		{"logstash:9600", "http://logstash:9600", true},
+		{"", "", false},
+		{"ftp://logstash:9600", "", false},
+		{"http://", "", false},
+	}
+
+	for _, test := range tests {
+		endpoint, err := probeEndpoint(test.target)
+		if test.valid && err != nil {
+			t.Errorf("%q: unexpected error: %v", test.target, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%q: expected an error", test.target)
+		}
+		if endpoint != test.endpoint {
+			t.Errorf("%q: expected endpoint %q, got %q", test.target, test.endpoint, endpoint)
+		}
+	}
+}
+
+func TestProbeHandler(t *testing.T) {
+	logstash := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_node":
+			w.Write([]byte(`{"version": "6.2.4"}`))
+		case "/_node/stats":
+			w.Write([]byte(`{"version": "6.2.4", "pipelines": {"main": {}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer logstash.Close()
+
+	body := probe(t, logstash.URL, "")
+	if !strings.Contains(body, "logstash_up 1") {
+		t.Errorf("expected logstash_up 1, got:\n%s", body)
+	}
+	if !strings.Contains(body, `logstash_info_node{version="6.2.4"} 1`) {
+		t.Errorf("expected node info for the probed target, got:\n%s", body)
+	}
+
+	logstash.Close()
+	body = probe(t, logstash.URL, "")
+	if !strings.Contains(body, "logstash_up 0") {
+		t.Errorf("expected logstash_up 0 for an unreachable target, got:\n%s", body)
+	}
+}
+
+func TestProbeHandlerTimeout(t *testing.T) {
+	blocked := make(chan struct{})
+	logstash := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-blocked
+	}))
+	defer logstash.Close()
+	defer close(blocked)
+
+	scrapeTimeoutOffset = 500 * time.Millisecond
+	defer func() { scrapeTimeoutOffset = 0 }()
+
+	begin := time.Now()
+	body := probe(t, logstash.URL, "0.6")
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Errorf("expected the probe to time out after 100ms, took %s", elapsed)
+	}
+	if !strings.Contains(body, "logstash_up 0") {
+		t.Errorf("expected logstash_up 0 for a hung target, got:\n%s", body)
+	}
+}
+
+func TestScrapeContext(t *testing.T) {
+	scrapeTimeoutOffset = 500 * time.Millisecond
+	defer func() { scrapeTimeoutOffset = 0 }()
+
+	tests := []struct {
+		header   string
+		timeout  time.Duration
+		expected time.Duration
+	}{
+		{"", 0, 0},
+		{"", 5 * time.Second, 5 * time.Second},
+		{"10", 0, 9500 * time.Millisecond},
+		{"10", 5 * time.Second, 5 * time.Second},
+		{"3", 5 * time.Second, 2500 * time.Millisecond},
+		{"0.2", 5 * time.Second, 5 * time.Second},
+		{"garbage", 5 * time.Second, 5 * time.Second},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/probe", nil)
+		if test.header != "" {
+			req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", test.header)
+		}
+
+		ctx, cancel := scrapeContext(req, test.timeout)
+		deadline, ok := ctx.Deadline()
+		cancel()
+
+		if test.expected == 0 {
+			if ok {
+				t.Errorf("header %q, timeout %s: expected no deadline", test.header, test.timeout)
+			}
+			continue
+		}
+
+		remaining := deadline.Sub(time.Now())
+		if remaining > test.expected || remaining < test.expected-100*time.Millisecond {
+			t.Errorf("header %q, timeout %s: expected a deadline in %s, got %s", test.header, test.timeout, test.expected, remaining)
+		}
+	}
+}
+
+func probe(t *testing.T, target, scrapeTimeout string) string {
+	req := httptest.NewRequest("GET", "/probe?target="+target, nil)
+	if scrapeTimeout != "" {
+		req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", scrapeTimeout)
+	}
+	rec := httptest.NewRecorder()
+	probeHandler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	body, err := ioutil.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"github.com/BonnierNews/logstash_exporter/collector"
+	"github.com/BonnierNews/logstash_exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+)
+
+var (
+	configReloadSuccess = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: collector.Namespace,
+			Subsystem: "exporter",
+			Name:      "config_last_reload_successful",
+			Help:      "logstash_exporter: Whether the last configuration reload attempt was successful.",
+		},
+	)
+
+	configReloadSeconds = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: collector.Namespace,
+			Subsystem: "exporter",
+			Name:      "config_last_reload_success_timestamp_seconds",
+			Help:      "logstash_exporter: Timestamp of the last successful configuration reload.",
+		},
+	)
+
+	exporterConfig = &safeConfig{C: &config.Config{}}
+)
+
+// maxURLTargets bounds how many targets probed by URL are kept around
+const maxURLTargets = 1000
+
+// safeConfig guards the configuration, which is replaced on reload, and the
+// targets built from it. Targets live until the next reload so that secrets
+// read from files are only read again when they change and responses can be
+// shared between scrapes.
+type safeConfig struct {
+	sync.RWMutex
+	reloading  sync.Mutex
+	C          *config.Config
+	targets    map[string]*collector.Target
+	urlTargets map[string]*collector.Target
+	filename   string
+}
+
+// get returns the configuration currently in use
+func (sc *safeConfig) get() *config.Config {
+	sc.RLock()
+	defer sc.RUnlock()
+	return sc.C
+}
+
+// target returns the configuration and the collector.Target of the target
+// configured under name
+func (sc *safeConfig) target(name string) (config.TargetConfig, *collector.Target, bool) {
+	sc.RLock()
+	defer sc.RUnlock()
+
+	targetConfig, ok := sc.C.Target(name)
+	if !ok {
+		return config.TargetConfig{}, nil, false
+	}
+	return targetConfig, sc.targets[name], true
+}
+
+// urlTarget returns the collector.Target for a Logstash API probed by URL
+// rather than by a configured name
+func (sc *safeConfig) urlTarget(targetConfig config.TargetConfig) (*collector.Target, error) {
+	sc.Lock()
+	defer sc.Unlock()
+
+	if target, ok := sc.urlTargets[targetConfig.URL]; ok {
+		return target, nil
+	}
+
+	target, err := newTarget(targetConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if sc.urlTargets == nil {
+		sc.urlTargets = make(map[string]*collector.Target)
+	}
+	if len(sc.urlTargets) < maxURLTargets {
+		sc.urlTargets[targetConfig.URL] = target
+	}
+	return target, nil
+}
+
+// reload reads the configuration file again. An invalid configuration is
+// rejected and the previous one stays live. Every target is built again, so
+// that rotated TLS files are read, but targets whose configuration did not
+// change keep the cache and the last snapshot of their predecessor. Without
+// a configuration file the reload trivially succeeds.
+func (sc *safeConfig) reload() error {
+	if sc.filename == "" {
+		configReloadSuccess.Set(1)
+		configReloadSeconds.Set(float64(time.Now().Unix()))
+		return nil
+	}
+
+	cfg, err := config.LoadFile(sc.filename)
+	if err == nil {
+		err = checkCollectors(cfg)
+	}
+	if err != nil {
+		configReloadSuccess.Set(0)
+		return fmt.Errorf("cannot load config file %s: %v", sc.filename, err)
+	}
+
+	sc.reloading.Lock()
+	defer sc.reloading.Unlock()
+
+	sc.RLock()
+	previousConfig, previous := sc.C, sc.targets
+	sc.RUnlock()
+	if previousConfig == nil {
+		previousConfig = &config.Config{}
+	}
+
+	targets := make(map[string]*collector.Target, len(cfg.Targets))
+	unchanged := make(map[string]bool)
+	for _, targetConfig := range cfg.Targets {
+		if targets[targetConfig.Name], err = newTarget(targetConfig); err != nil {
+			configReloadSuccess.Set(0)
+			return fmt.Errorf("cannot load config file %s: target %q: %v", sc.filename, targetConfig.Name, err)
+		}
+
+		previousTargetConfig, ok := previousConfig.Target(targetConfig.Name)
+		unchanged[targetConfig.Name] = ok && previous[targetConfig.Name] != nil && reflect.DeepEqual(previousTargetConfig, targetConfig)
+	}
+
+	// Previous targets are served from their last snapshot until replaced
+	for name, target := range previous {
+		if target.Poller != nil {
+			target.Poller.Stop()
+		}
+		if unchanged[name] {
+			targets[name].Adopt(target)
+		}
+	}
+
+	sc.Lock()
+	sc.C = cfg
+	sc.targets = targets
+	sc.urlTargets = nil
+	sc.Unlock()
+
+	for _, target := range targets {
+		if target.Poller != nil {
+			target.Poller.Start()
+		}
+	}
+
+	configReloadSuccess.Set(1)
+	configReloadSeconds.Set(float64(time.Now().Unix()))
+	return nil
+}
+
+// checkCollectors makes sure every target only enables known collectors
+func checkCollectors(cfg *config.Config) error {
+	for _, target := range cfg.Targets {
+		for _, name := range target.Collectors {
+			if _, ok := collectorFactories[name]; !ok {
+				return fmt.Errorf("target %q: unknown collector %q", target.Name, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func reloadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "This endpoint requires a POST request.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := exporterConfig.reload(); err != nil {
+		log.Errorf("Error reloading config: %v", err)
+		http.Error(w, fmt.Sprintf("failed to reload config: %s", err), http.StatusInternalServerError)
+		return
+	}
+	log.Infoln("Reloaded config file", exporterConfig.filename)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/pem"
+	"fmt"
+	"github.com/BonnierNews/logstash_exporter/collector"
+	dto "github.com/prometheus/client_model/go"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReloadKeepsPreviousConfigOnError(t *testing.T) {
+	file, err := ioutil.TempFile("", "logstash_exporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	sc := &safeConfig{filename: file.Name()}
+
+	writeFile(t, file.Name(), "targets: [{name: a, url: 'http://a:9600'}]")
+	if err := sc.reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, file.Name(), "targets: [{name: b, url: 'http://b:9600', collectors: [unknown]}]")
+	if err := sc.reload(); err == nil {
+		t.Fatal("expected an error for an unknown collector")
+	}
+
+	if _, ok := sc.get().Target("a"); !ok {
+		t.Error("expected the previous configuration to stay live")
+	}
+}
+
+func TestReloadKeepsSnapshotsOfUnchangedTargets(t *testing.T) {
+	var failing int32
+	logstash := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"version": "6.2.4"}`))
+	}))
+	defer logstash.Close()
+
+	file, err := ioutil.TempFile("", "logstash_exporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	sc := &safeConfig{filename: file.Name()}
+
+	writeFile(t, file.Name(), fmt.Sprintf("targets: [{name: a, url: '%s', poll_interval: 1h}, {name: b, url: '%s', poll_interval: 1h}]", logstash.URL, logstash.URL))
+	if err := sc.reload(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b"} {
+		_, target, _ := sc.target(name)
+		waitForSnapshot(t, target)
+	}
+
+	atomic.StoreInt32(&failing, 1)
+	writeFile(t, file.Name(), fmt.Sprintf("targets: [{name: a, url: '%s', poll_interval: 1h}, {name: b, url: '%s', poll_interval: 2h}]", logstash.URL, logstash.URL))
+	if err := sc.reload(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, name := range []string{"a", "b"} {
+			_, target, _ := sc.target(name)
+			target.Poller.Stop()
+		}
+	}()
+
+	_, a, _ := sc.target("a")
+	if stats, _, err := a.Poller.Snapshot(); err != nil || stats.Version != "6.2.4" {
+		t.Errorf("expected the unchanged target to keep its snapshot, got %+v, %v", stats, err)
+	}
+	_, b, _ := sc.target("b")
+	if _, _, err := b.Poller.Snapshot(); err == nil {
+		t.Error("expected the changed target to start without a snapshot")
+	}
+}
+
+func TestReloadReadsTLSFilesAgain(t *testing.T) {
+	logstash := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer logstash.Close()
+
+	dir, err := ioutil.TempDir("", "logstash_exporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configFile, caFile := filepath.Join(dir, "config.yml"), filepath.Join(dir, "ca.pem")
+	writeFile(t, caFile, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: logstash.TLS.Certificates[0].Certificate[0]})))
+	writeFile(t, configFile, fmt.Sprintf("targets: [{name: a, url: '%s', tls_config: {ca_file: %s}}]", logstash.URL, caFile))
+
+	sc := &safeConfig{filename: configFile}
+	if err := sc.reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, caFile, "rotated, but not a certificate")
+	if err := sc.reload(); err == nil {
+		t.Error("expected the CA file to be read again on reload")
+	}
+}
+
+func TestReloadWithoutConfigFile(t *testing.T) {
+	configReloadSuccess.Set(0)
+
+	sc := &safeConfig{}
+	if err := sc.reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	var m dto.Metric
+	if err := configReloadSuccess.Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	if value := m.Gauge.GetValue(); value != 1 {
+		t.Errorf("expected the reload without a config file to be successful, got %v", value)
+	}
+}
+
+func writeFile(t *testing.T, filename, content string) {
+	if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitForSnapshot(t *testing.T, target *collector.Target) {
+	for i := 0; i < 100; i++ {
+		if _, _, err := target.Poller.Snapshot(); err == nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("expected a snapshot to be polled")
+}
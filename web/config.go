@@ -0,0 +1,100 @@
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+)
+
+// Config is the web configuration file, in the format of the Prometheus
+// exporter toolkit
+type Config struct {
+	TLSServerConfig *TLSServerConfig  `yaml:"tls_server_config,omitempty"`
+	BasicAuthUsers  map[string]string `yaml:"basic_auth_users,omitempty"`
+}
+
+// TLSServerConfig configures TLS on the exporter's listeners
+type TLSServerConfig struct {
+	CertFile       string `yaml:"cert_file"`
+	KeyFile        string `yaml:"key_file"`
+	ClientAuthType string `yaml:"client_auth_type,omitempty"`
+	ClientCAFile   string `yaml:"client_ca_file,omitempty"`
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":                           tls.NoClientCert,
+	"NoClientCert":               tls.NoClientCert,
+	"RequestClientCert":          tls.RequestClientCert,
+	"RequireAnyClientCert":       tls.RequireAnyClientCert,
+	"VerifyClientCertIfGiven":    tls.VerifyClientCertIfGiven,
+	"RequireAndVerifyClientCert": tls.RequireAndVerifyClientCert,
+}
+
+// LoadConfigFile parses and validates the web configuration file at filename
+func LoadConfigFile(filename string) (*Config, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict(content, cfg); err != nil {
+		return nil, err
+	}
+
+	for user, hash := range cfg.BasicAuthUsers {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("basic_auth_users: invalid bcrypt hash for user %q: %v", user, err)
+		}
+	}
+
+	if cfg.TLSServerConfig != nil {
+		if _, err := cfg.TLSServerConfig.tlsConfig(); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+func (c *TLSServerConfig) tlsConfig() (*tls.Config, error) {
+	if c.CertFile == "" || c.KeyFile == "" {
+		return nil, fmt.Errorf("tls_server_config: cert_file and key_file are required")
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("tls_server_config: cannot load certificate %s and key %s: %v", c.CertFile, c.KeyFile, err)
+	}
+
+	clientAuth, ok := clientAuthTypes[c.ClientAuthType]
+	if !ok {
+		return nil, fmt.Errorf("tls_server_config: invalid client_auth_type %q", c.ClientAuthType)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   clientAuth,
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if c.ClientCAFile != "" {
+		ca, err := ioutil.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls_server_config: cannot read client CA file %s: %v", c.ClientCAFile, err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("tls_server_config: no certificates found in client CA file %s", c.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+	} else if clientAuth == tls.VerifyClientCertIfGiven || clientAuth == tls.RequireAndVerifyClientCert {
+		return nil, fmt.Errorf("tls_server_config: client_auth_type %s requires client_ca_file", c.ClientAuthType)
+	}
+
+	return tlsConfig, nil
+}
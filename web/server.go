@@ -0,0 +1,94 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/prometheus/common/log"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"sync"
+)
+
+// ListenAndServe starts server, applying TLS and basic authentication from
+// the web configuration file at configFile unless it is empty
+func ListenAndServe(server *http.Server, configFile string) error {
+	if configFile == "" {
+		return server.ListenAndServe()
+	}
+
+	cfg, err := LoadConfigFile(configFile)
+	if err != nil {
+		return err
+	}
+
+	if len(cfg.BasicAuthUsers) > 0 {
+		server.Handler = &basicAuthHandler{
+			handler: server.Handler,
+			users:   cfg.BasicAuthUsers,
+			cache:   make(map[string]bool),
+		}
+	}
+
+	if cfg.TLSServerConfig == nil {
+		return server.ListenAndServe()
+	}
+
+	if server.TLSConfig, err = cfg.TLSServerConfig.tlsConfig(); err != nil {
+		return err
+	}
+	log.Infoln("TLS is enabled on", server.Addr)
+	return server.ListenAndServeTLS("", "")
+}
+
+// basicAuthHandler requires requests to carry the credentials of one of the
+// configured users. bcrypt is slow by design, so successful comparisons are
+// cached.
+type basicAuthHandler struct {
+	handler http.Handler
+	users   map[string]string
+
+	mtx   sync.Mutex
+	cache map[string]bool
+}
+
+// dummyHash is compared against for unknown users, so that they take as long
+// to reject as known users with a wrong password
+var dummyHash = []byte("$2y$10$QOauhQNbBCuQDKes6eFzPeMqBSjb7Mr5DUmpZ/VcEd00UAV/LDeSi")
+
+func (h *basicAuthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, password, ok := r.BasicAuth()
+	if !ok || !h.authorized(user, password) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="logstash_exporter"`)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	h.handler.ServeHTTP(w, r)
+}
+
+func (h *basicAuthHandler) authorized(user, password string) bool {
+	hash, known := h.users[user]
+	if !known {
+		hash = string(dummyHash)
+	}
+
+	sum := sha256.Sum256([]byte(user + ":" + hash + ":" + password))
+	key := hex.EncodeToString(sum[:])
+
+	h.mtx.Lock()
+	cached := h.cache[key]
+	h.mtx.Unlock()
+	if cached {
+		return true
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil || !known {
+		return false
+	}
+
+	h.mtx.Lock()
+	h.cache[key] = true
+	h.mtx.Unlock()
+
+	return true
+}
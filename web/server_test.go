@@ -0,0 +1,71 @@
+package web
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// secretHash is the bcrypt hash of "secret" with the minimum cost
+const secretHash = "$2a$04$1s0QqbBdWa09pqLH7SqtbuUMKJGozFDsJ9HyqAQW7oYmYhK0MD3IG"
+
+func TestBasicAuthHandler(t *testing.T) {
+	handler := &basicAuthHandler{
+		handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		users:   map[string]string{"prometheus": secretHash},
+		cache:   make(map[string]bool),
+	}
+
+	tests := []struct {
+		user     string
+		password string
+		status   int
+	}{
+		{"prometheus", "secret", http.StatusOK},
+		{"prometheus", "secret", http.StatusOK},
+		{"prometheus", "wrong", http.StatusUnauthorized},
+		{"unknown", "secret", http.StatusUnauthorized},
+		{"", "", http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/metrics", nil)
+		if test.user != "" {
+			req.SetBasicAuth(test.user, test.password)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != test.status {
+			t.Errorf("%s:%s: expected status %d, got %d", test.user, test.password, test.status, rec.Code)
+		}
+	}
+}
+
+func TestLoadConfigFileInvalid(t *testing.T) {
+	tests := map[string]string{
+		"invalid hash":     "basic_auth_users: {prometheus: plaintext}",
+		"missing key":      "tls_server_config: {cert_file: /nonexistent.pem}",
+		"unknown field":    "basic_auth_user: {prometheus: plaintext}",
+		"missing cert":     "tls_server_config: {cert_file: /nonexistent.pem, key_file: /nonexistent-key.pem}",
+		"malformed config": "basic_auth_users: [",
+	}
+
+	for name, content := range tests {
+		file, err := ioutil.TempFile("", "web-config")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(file.Name())
+
+		if err := ioutil.WriteFile(file.Name(), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadConfigFile(file.Name()); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}